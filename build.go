@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/alecthomas/chroma/v2"
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -19,7 +25,12 @@ type Page struct {
 	RelPath     string      // Relative path from source root, e.g. "guide/02-agents.md"
 	Frontmatter Frontmatter // Parsed YAML frontmatter
 	Body        []byte      // Markdown body (without frontmatter)
+	BodyLine    int         // Line in the source file where Body starts (1-based)
+	Status      string      // "draft", "future", or "expired" if built only because of --drafts, --future, or --expired
+	DateTime    time.Time   // Parsed date in the site time zone; zero if unset or invalid
+	LastmodTime time.Time   // Parsed lastmod, falling back to DateTime
 	HTML        []byte      // Rendered HTML (set after shortcode + markdown processing)
+	Unresolved  []string    // [[wikilink]] targets that matched no page (set during render)
 }
 
 // PageMeta is a lightweight page summary available to templates and shortcodes.
@@ -27,36 +38,83 @@ type PageMeta struct {
 	Title       string
 	Description string
 	URL         string
-	Date        string
+	Date        string    // Raw frontmatter date, e.g. "2026-03-18 14:30"
+	DateTime    time.Time // Parsed Date in the site time zone; zero if unset or invalid
+	Lastmod     string    // Raw lastmod, falling back to Date
+	LastmodTime time.Time // Parsed Lastmod
 	Extra       map[string]any
-	Section     string // Top-level directory, e.g. "guide" (empty for root pages)
+	Section     string                // Top-level directory, e.g. "guide" (empty for root pages)
+	SectionPath string                // Full directory path, e.g. "guide/advanced" (empty for root pages)
+	Terms       map[string][]TermLink // Taxonomy name → this page's terms, e.g. .Terms.tags
 }
 
 // TemplateData is passed to the layout template.
 type TemplateData struct {
 	Title         string
 	Description   string
-	Date          string // Page date from frontmatter (raw string, e.g. "2026-03-18 14:30")
+	Date          string    // Page date from frontmatter (raw string, e.g. "2026-03-18 14:30")
+	PublishDate   string    // publish_date from frontmatter (raw string)
+	ExpiryDate    string    // expiry_date from frontmatter (raw string)
+	DateTime      time.Time // Parsed Date in the site time zone; zero if unset or invalid
+	Lastmod       string    // lastmod from frontmatter, falling back to Date (raw string)
+	LastmodTime   time.Time // Parsed Lastmod
+	Status        string    // "draft", "future", or "expired" if built only because of --drafts, --future, or --expired
 	Content       template.HTML
 	Nav           template.HTML
+	NavTree       []NavItem // Nav tree with Active/Ancestor set for this page
+	Breadcrumbs   []NavItem // Trail from the top-level section to this page (empty if not in nav)
+	TOC           TOC       // Headings of this page, for an "On this page" nav
+	Prev          *PageMeta // Previous page in nav order, nil at the start
+	Next          *PageMeta // Next page in nav order, nil at the end
 	Footer        template.HTML
 	CurrentPath   string
 	SiteName      string
 	BasePath      string
-	Logo          string         // Path to logo image (relative to BasePath)
-	LogoInline    template.HTML  // Inlined SVG content (set when logo is .svg)
-	Favicon       string         // Path to favicon (relative to BasePath)
-	SearchEnabled bool           // Whether built-in search UI should render
-	FeedEnabled   bool           // Whether RSS feed is enabled
-	TopNav        []LinkConfig   // Top navigation links
-	TopNavMore    []LinkConfig   // Dropdown items under More
-	Extra         map[string]any // Per-page extra frontmatter
-	Site          map[string]any // Site-level extra from config.toml [extra]
-	Pages         []PageMeta     // All non-draft pages (sorted by date desc, then title)
+	Logo          string                // Path to logo image (relative to BasePath)
+	LogoInline    template.HTML         // Inlined SVG content (set when logo is .svg)
+	Favicon       string                // Path to favicon (relative to BasePath)
+	SearchEnabled bool                  // Whether built-in search UI should render
+	FeedEnabled   bool                  // Whether RSS feed is enabled
+	TopNav        []LinkConfig          // Top navigation links
+	TopNavMore    []LinkConfig          // Dropdown items under More
+	Extra         map[string]any        // Per-page extra frontmatter
+	Site          map[string]any        // Site-level extra from config.toml [extra]
+	Pages         []PageMeta            // All non-draft pages (sorted by date desc, then title)
+	Data          map[string]any        // Site data from _data/, keyed by file name
+	Taxonomies    map[string]Taxonomy   // Configured taxonomies by name, e.g. .Taxonomies.tags
+	Terms         map[string][]TermLink // This page's terms by taxonomy name
+	Taxonomy      *Taxonomy             // On taxonomy and term pages, the taxonomy listed
+	Term          *Term                 // On term pages, the term listed
+	Paginator     *Paginator            // On a paginated section index.md and on term pages, this page of the list
 }
 
 // Build reads markdown from src, renders HTML, and writes to dst.
+// With cfg.Strict set, the built site is also checked and any problems
+// fail the build.
 func Build(src, dst string, cfg Config) error {
+	site, err := buildSite(src, dst, cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Strict {
+		if issues := checkSite(site); len(issues) > 0 {
+			printIssues(issues)
+			return fmt.Errorf("strict: %d problem(s) found", len(issues))
+		}
+	}
+	return nil
+}
+
+// siteBuild describes a finished build, for post-build checks.
+type siteBuild struct {
+	Src, Dst string
+	BasePath string
+	Pages    []Page // Built pages, with HTML and Unresolved set
+	Drafts   []Page // Pages skipped as drafts, scheduled, or expired
+}
+
+// buildSite runs the build pipeline and reports what it produced.
+func buildSite(src, dst string, cfg Config) (*siteBuild, error) {
 	src, _ = filepath.Abs(src)
 	dst, _ = filepath.Abs(dst)
 
@@ -67,20 +125,59 @@ func Build(src, dst string, cfg Config) error {
 		siteName = "Site"
 	}
 
+	// Dates without an offset are read in the configured time zone
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	// Template functions shared by layouts and shortcodes
+	funcs := newTemplateFuncs(cfg)
+
+	// Partials are shared by layouts and shortcodes
+	partials, err := loadPartials(src)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load layout templates (base + named variants)
-	layouts, err := loadLayouts(src)
+	layouts, err := loadLayouts(src, funcs, partials)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Load shortcode templates
-	shortcodes, err := loadShortcodes(src)
+	shortcodes, err := loadShortcodes(src, funcs, partials)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Load site data files
+	siteData, dataDigest, err := loadData(src)
+	if err != nil {
+		return nil, err
 	}
 
 	// Discover and parse markdown files
-	var pages []Page
+	var pages, drafts []Page
+	sourceDigests := make(map[string]string)
+	defaults := newDirDefaults(src)
+	now := time.Now()
+	// Frontmatter errors are collected so that one build reports them all;
+	// with lenient set they're only warnings
+	var fmProblems []string
+	reportFrontmatter := func(err error) error {
+		var fmErr *FrontmatterError
+		if !errors.As(err, &fmErr) {
+			return err
+		}
+		if cfg.Lenient {
+			fmt.Printf("  Warning: %v\n", err)
+		} else {
+			fmProblems = append(fmProblems, err.Error())
+		}
+		return nil
+	}
 	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -94,57 +191,178 @@ func Build(src, dst string, cfg Config) error {
 			return nil
 		}
 
-		if d.IsDir() || !strings.HasSuffix(name, ".md") {
+		relPath, _ := filepath.Rel(src, path)
+		if d.IsDir() {
+			if err := defaults.enter(relPath); err != nil {
+				return reportFrontmatter(err)
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".md") {
 			return nil
 		}
 
-		relPath, _ := filepath.Rel(src, path)
 		content, err := os.ReadFile(path)
 		if err != nil {
 			return fmt.Errorf("reading %s: %w", relPath, err)
 		}
 
-		fm, body := ParseFrontmatter(content)
-
-		// Skip draft pages
-		if fm.Draft {
-			fmt.Printf("  Skipping draft: %s\n", relPath)
-			return nil
+		pageDefaults := defaults.forPage(relPath)
+		fm, body, err := parseFrontmatter(content, pageDefaults)
+		if err != nil {
+			if err := reportFrontmatter(withFrontmatterFile(err, relPath)); err != nil {
+				return err
+			}
+			if !cfg.Lenient {
+				return nil
+			}
 		}
 
 		// Warn on malformed dates
-		if fm.Date != "" {
-			if _, ok := ParseDate(fm.Date); !ok {
-				fmt.Printf("  Warning: %s has invalid date %q (expected YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)\n", relPath, fm.Date)
+		for _, d := range []struct{ field, value string }{
+			{"date", fm.Date}, {"publish_date", fm.PublishDate}, {"expiry_date", fm.ExpiryDate}, {"lastmod", fm.Lastmod},
+		} {
+			if d.value == "" {
+				continue
+			}
+			if _, ok := parseDateIn(d.value, loc); !ok {
+				fmt.Printf("  Warning: %s has invalid %s %q (expected YYYY-MM-DD, YYYY-MM-DD HH:MM:SS, or RFC 3339)\n", relPath, d.field, d.value)
+			}
+		}
+
+		// Skip drafts, scheduled, and expired pages unless asked for
+		statuses := pageStatuses(fm, now, loc)
+		for _, status := range statuses {
+			var skip string
+			switch {
+			case status == statusDraft && !cfg.Drafts:
+				skip = "draft"
+			case status == statusFuture && !cfg.Future:
+				skip = "scheduled page"
+			case status == statusExpired && !cfg.Expired:
+				skip = "expired page"
+			default:
+				continue
 			}
+			fmt.Printf("  Skipping %s: %s\n", skip, relPath)
+			drafts = append(drafts, Page{RelPath: relPath, Frontmatter: fm})
+			return nil
+		}
+		var status string
+		if len(statuses) > 0 {
+			status = statuses[0]
+		}
+
+		if _, _, err := parseTOCLevels(fm.TOCLevels); err != nil {
+			fmt.Printf("  Warning: %s has %v\n", relPath, err)
+		}
+		if !validNavOrder(fm.NavOrder) {
+			fmt.Printf("  Warning: %s has invalid nav_order %q (expected date, title, or weight)\n", relPath, fm.NavOrder)
 		}
 
 		// Body stored raw — shortcodes processed per-page during render
-		pages = append(pages, Page{
+		sourceDigests[relPath] = sourceDigest(content, pageDefaults)
+		if status != "" {
+			// The status banner goes away once a scheduled page is due
+			sourceDigests[relPath] = digest([]byte(sourceDigests[relPath]), []byte(status))
+		}
+		page := Page{
 			RelPath:     relPath,
 			Frontmatter: fm,
 			Body:        body,
-		})
+			BodyLine:    bytes.Count(content[:len(content)-len(body)], []byte("\n")) + 1,
+			Status:      status,
+		}
+		page.parseDates(loc)
+		pages = append(pages, page)
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("walking source: %w", err)
+		return nil, fmt.Errorf("walking source: %w", err)
+	}
+	if len(fmProblems) > 0 {
+		return nil, fmt.Errorf("invalid frontmatter (set lenient = true to build anyway):\n  %s", strings.Join(fmProblems, "\n  "))
 	}
 
 	fmt.Printf("Found %d pages\n", len(pages))
 
-	// Build navigation
-	nav := BuildNav(pages)
+	// Refuse to let one output silently overwrite another. Generated names are
+	// reserved even when their feature is off, since disabling one removes it.
+	generated := []string{syntaxCSSFilename, searchIndexFilename, feedFilename, "_static/"}
+	taxPaths, err := taxonomyPaths(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range taxPaths {
+		generated = append(generated, p+"/")
+	}
+	if err := checkURLCollisions(pages, generated); err != nil {
+		return nil, err
+	}
+
+	// Build navigation: curated from _nav.yaml or [[nav]] if given,
+	// otherwise from the directory structure
+	navEntries, err := loadNavFile(src)
+	if err != nil {
+		return nil, err
+	}
+	if navEntries != nil && cfg.Nav != nil {
+		return nil, fmt.Errorf("nav is defined in both %s and config [[nav]]; keep one", navFilename)
+	}
+	if navEntries == nil {
+		navEntries = cfg.Nav
+	}
+	var nav []NavItem
+	if navEntries != nil {
+		var orphans []string
+		nav, orphans, err = BuildExplicitNav(navEntries, pages)
+		if err != nil {
+			return nil, err
+		}
+		for _, relPath := range orphans {
+			fmt.Printf("  Warning: %s is not in the nav\n", relPath)
+		}
+	} else {
+		nav = BuildNav(pages)
+	}
 
 	// Build wikilink resolver from discovered pages
 	wikiResolver := newPageResolver(pages, basePath)
+	wikiResolver.headingAnchors = cfg.HeadingAnchors
+	funcs.md = wikiResolver
+	templates := newTemplatePool(funcs, layouts, shortcodes)
 
-	// Build page metadata list for templates and shortcodes
+	// Build page metadata list for templates and shortcodes, with the
+	// taxonomy terms of each page
 	allPages := buildPageMeta(pages, basePath)
+	taxonomies, err := buildTaxonomies(taxPaths, allPages, basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the lists of paginated section index pages
+	pagers := sectionPagers(pages, allPages, basePath)
+	if err := checkPagerCollisions(pages, pagers, basePath); err != nil {
+		return nil, err
+	}
+
+	// Reading order for prev/next links, following the nav
+	metaByURL := make(map[string]*PageMeta, len(allPages))
+	for i := range allPages {
+		metaByURL[allPages[i].URL] = &allPages[i]
+	}
+	var navOrder []*PageMeta
+	navIndex := make(map[string]int)
+	for _, u := range navPageOrder(nav) {
+		if meta, ok := metaByURL[basePath+u]; ok {
+			navIndex[u] = len(navOrder)
+			navOrder = append(navOrder, meta)
+		}
+	}
 
 	// Generate syntax highlighting CSS
 	if err := writeSyntaxCSS(dst, cfg.Highlight); err != nil {
-		return fmt.Errorf("writing syntax CSS: %w", err)
+		return nil, fmt.Errorf("writing syntax CSS: %w", err)
 	}
 
 	// Read inline SVG logo if configured
@@ -158,27 +376,55 @@ func Build(src, dst string, cfg Config) error {
 
 	footer, err := buildFooter(cfg)
 	if err != nil {
-		return fmt.Errorf("building footer: %w", err)
+		return nil, fmt.Errorf("building footer: %w", err)
 	}
 
-	// Render each page
-	for i, page := range pages {
-		currentPath := pageURL(page)
-		prefixedPath := basePath + currentPath
-		outPath := outputPathFromURL(dst, currentPath)
-
-		navHTML := RenderNav(nav, prefixedPath, basePath, cfg.Links)
+	// What every template sees besides the page itself. If any of it changes
+	// (config, footer, data files, nav, or another page's title, URL,
+	// section, or terms), every page re-renders; otherwise only pages whose
+	// own inputs changed do. Settings that only affect how the build runs
+	// are left out. The rest of other pages' metadata (descriptions, dates,
+	// extra) is only read through the page list, prev/next, or a paginator,
+	// so it is digested per page, for the pages that read it.
+	siteCfg := cfg
+	siteCfg.Cache = nil
+	siteCfg.Jobs = 0
+	type pageSummary struct {
+		Title, URL, Section, SectionPath string
+		Terms                            map[string][]TermLink
+	}
+	summaries := make([]pageSummary, len(allPages))
+	for i, m := range allPages {
+		summaries[i] = pageSummary{m.Title, m.URL, m.Section, m.SectionPath, m.Terms}
+	}
+	siteDigest := digestJSON(struct {
+		Config    Config
+		SiteName  string
+		BasePath  string
+		Nav       []NavItem
+		Pages     []pageSummary
+		Wikilinks map[string]string
+		Paths     map[string]string
+		Logo      string
+		Footer    string
+		Data      string
+	}{siteCfg, siteName, basePath, nav, summaries, wikiResolver.pages, wikiResolver.paths, string(logoInline), string(footer), dataDigest})
+	pagesDigest := digestJSON(allPages)
 
-		title := page.Frontmatter.Title
-		if title == "" {
-			title = TitleFromFilename(filepath.Base(page.RelPath))
-		}
+	prevCache := newBuildCache("")
+	if cfg.CacheEnabled() {
+		prevCache = loadCache(dst)
+	}
+	nextCache := newBuildCache(siteDigest)
+	unchanged := 0
 
-		data := TemplateData{
-			Title:         title,
-			Description:   page.Frontmatter.Description,
-			Date:          page.Frontmatter.Date,
-			Nav:           template.HTML(navHTML),
+	// Template data every page shares; each page then fills in its own
+	siteTemplateData := func(prefixedPath string) TemplateData {
+		navTree := markNav(nav, prefixedPath, basePath)
+		return TemplateData{
+			Nav:           template.HTML(RenderNav(nav, prefixedPath, basePath, cfg.Links)),
+			NavTree:       navTree,
+			Breadcrumbs:   navBreadcrumbs(navTree),
 			Footer:        footer,
 			CurrentPath:   prefixedPath,
 			SiteName:      siteName,
@@ -190,50 +436,255 @@ func Build(src, dst string, cfg Config) error {
 			FeedEnabled:   cfg.FeedEnabled(),
 			TopNav:        cfg.TopNav,
 			TopNavMore:    cfg.TopNavMore,
-			Extra:         page.Frontmatter.Extra,
 			Site:          cfg.Extra,
 			Pages:         allPages,
+			Data:          siteData,
+			Taxonomies:    taxonomies,
+		}
+	}
+
+	// Render each page. Pages are independent, so they render on a bounded
+	// worker pool; log lines are buffered per page and replayed in page order
+	// so output and the first reported error match a sequential build.
+	// A paginated index.md renders once per page of its list; later pages
+	// are cached under their URL.
+	renderOne := func(page Page, pager *Paginator) (res renderResult) {
+		var log strings.Builder
+		defer func() { res.log = log.String() }()
+
+		currentPath := pageURL(page)
+		prefixedPath := basePath + currentPath
+		outPath := outputPathFromURL(dst, currentPath)
+		cacheKey := page.RelPath
+		if pager != nil && pager.PageNumber > 1 {
+			cacheKey = strings.TrimPrefix(pager.URL, basePath)
+			outPath = outputPathFromURL(dst, cacheKey)
+		}
+
+		// Pick layout: frontmatter "layout: name" → _layout.name.html, then a
+		// variant named after the page's section, default → _layout.html
+		layoutName := page.Frontmatter.Layout
+		if layoutName == "" {
+			layoutName = sectionLayout(layouts, page.RelPath)
+		}
+		if _, ok := layouts.get(layoutName); !ok {
+			if layoutName == "" {
+				res.err = fmt.Errorf("missing default layout _layout.html")
+			} else {
+				res.err = fmt.Errorf("page %s requests layout %q but _layout.%s.html not found", page.RelPath, layoutName, layoutName)
+			}
+			return res
+		}
+
+		var prev, next *PageMeta
+		if i, ok := navIndex[currentPath]; ok {
+			if i > 0 {
+				prev = navOrder[i-1]
+			}
+			if i < len(navOrder)-1 {
+				next = navOrder[i+1]
+			}
+		}
+
+		// Other pages' metadata this page can read: its neighbours and
+		// paginator items, and every page if a template lists them
+		deps := shortcodes.shortcodeDeps(page.Body)
+		var listed string
+		if layouts.listsPages[layoutName] || shortcodes.anyListsPages(deps) {
+			listed = pagesDigest
+		}
+		relOut, _ := filepath.Rel(dst, outPath)
+		res.entry = cacheEntry{
+			Source:     sourceDigests[page.RelPath],
+			Layout:     layouts.digests[layoutName],
+			Shortcodes: deps,
+			Meta: digestJSON(struct {
+				Pages      string
+				Prev, Next *PageMeta
+				Paginator  *Paginator
+			}{listed, prev, next, pager}),
+			Output: relOut,
+		}
+		if prev, ok := prevCache.lookup(siteDigest, cacheKey, res.entry, src, dst); ok {
+			res.entry = prev
+			res.html = []byte(prev.HTML)
+			res.cached = true
+			return res
+		}
+
+		title := page.Frontmatter.Title
+		if title == "" {
+			title = TitleFromFilename(filepath.Base(page.RelPath))
 		}
 
-		// Process shortcodes in markdown source (before markdown rendering)
-		body, err := shortcodes.ProcessShortcodes(page.Body, &data, wikiResolver)
+		data := siteTemplateData(prefixedPath)
+		if pager != nil {
+			// Later pages of the list live at their own URL; nav still
+			// marks the page the list belongs to
+			data.CurrentPath = pager.URL
+		}
+		data.Title = title
+		data.Description = page.Frontmatter.Description
+		data.Date = page.Frontmatter.Date
+		data.PublishDate = page.Frontmatter.PublishDate
+		data.ExpiryDate = page.Frontmatter.ExpiryDate
+		data.DateTime = page.DateTime
+		data.Lastmod = pageLastmod(page)
+		data.LastmodTime = page.LastmodTime
+		data.Status = page.Status
+		data.Prev, data.Next = prev, next
+		data.Extra = page.Frontmatter.Extra
+		data.Paginator = pager
+		if meta, ok := metaByURL[prefixedPath]; ok {
+			data.Terms = meta.Terms
+		}
+
+		// Process shortcodes in markdown source (before markdown rendering),
+		// with templates whose markdownify renders as part of this page
+		md := wikiResolver.forPage(page.RelPath, &log)
+		tmpls, err := templates.get(md)
 		if err != nil {
-			return fmt.Errorf("processing shortcodes in %s: %w", page.RelPath, err)
+			res.err = err
+			return res
+		}
+		defer templates.put(tmpls)
+		files := shortcodes.files()
+		body, err := shortcodes.process(page.Body, &data, md, files, tmpls.shortcodes)
+		if err != nil {
+			var scErr *ShortcodeError
+			if errors.As(err, &scErr) {
+				// Report the position in the source file, not the body
+				scErr.File = page.RelPath
+				scErr.Line += page.BodyLine - 1
+				res.err = fmt.Errorf("processing shortcodes: %w", err)
+			} else {
+				res.err = fmt.Errorf("processing shortcodes in %s: %w", page.RelPath, err)
+			}
+			return res
 		}
 
-		// Render markdown to HTML (with wiki link resolution)
-		html, err := RenderMarkdownWithResolver(body, wikiResolver)
+		// Render markdown to HTML (with wiki link and .md link resolution)
+		html, err := md.render(body)
 		if err != nil {
-			return fmt.Errorf("rendering %s: %w", page.RelPath, err)
+			res.err = fmt.Errorf("rendering %s: %w", page.RelPath, err)
+			return res
 		}
-		pages[i].HTML = html
 		data.Content = template.HTML(html)
+		if page.Frontmatter.TOC == nil || *page.Frontmatter.TOC {
+			minLevel, maxLevel, _ := parseTOCLevels(page.Frontmatter.TOCLevels)
+			data.TOC = buildTOC(md.headings, html, minLevel, maxLevel)
+		}
 
-		// Pick layout: frontmatter "layout: name" → _layout.name.html, default → _layout.html
-		layoutName := page.Frontmatter.Layout
-		tmpl, ok := layouts[layoutName]
-		if !ok {
-			if layoutName == "" {
-				return fmt.Errorf("missing default layout _layout.html")
+		if err := renderToFile(tmpls.layouts[layoutName], data, outPath); err != nil {
+			res.err = fmt.Errorf("writing %s: %w", outPath, err)
+			return res
+		}
+		fmt.Fprintf(&log, "  %s → %s\n", page.RelPath, outPath)
+
+		res.html = html
+		res.entry.HTML = string(html)
+		res.entry.Unresolved = md.unresolved
+		if len(files.reads) > 0 {
+			res.entry.Files = files.reads
+		}
+		return res
+	}
+
+	// A paginated index.md is several jobs, so each job renders its own copy
+	// of the page while collect writes results back into pages
+	type renderJob struct {
+		index int        // Into pages
+		page  Page       // Copy of pages[index]
+		pager *Paginator // Page of the list, for a paginated index.md
+	}
+	var jobs []renderJob
+	for i, page := range pages {
+		if list, ok := pagers[page.RelPath]; ok {
+			for j := range list {
+				jobs = append(jobs, renderJob{index: i, page: page, pager: &list[j]})
 			}
-			return fmt.Errorf("page %s requests layout %q but _layout.%s.html not found", page.RelPath, layoutName, layoutName)
+			continue
+		}
+		jobs = append(jobs, renderJob{index: i, page: page})
+	}
+	err = renderParallel(len(jobs), cfg.EffectiveJobs(), func(i int) renderResult {
+		return renderOne(jobs[i].page, jobs[i].pager)
+	}, func(i int, res renderResult) {
+		if pager := jobs[i].pager; pager != nil && pager.PageNumber > 1 {
+			nextCache.Pages[strings.TrimPrefix(pager.URL, basePath)] = res.entry
+			return
+		}
+		page := &pages[jobs[i].index]
+		page.HTML = res.html
+		page.Unresolved = res.entry.Unresolved
+		nextCache.Pages[page.RelPath] = res.entry
+		if res.cached {
+			unchanged++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Render taxonomy and term pages, which list the pages above
+	listings := taxonomyPages(taxonomies, basePath, cfg.Paginate)
+	renderGenerated := func(g generatedPage) (res renderResult) {
+		prefixedPath := basePath + g.URL
+		outPath := outputPathFromURL(dst, g.URL)
+		if _, ok := layouts.get(g.Layout); !ok {
+			res.err = fmt.Errorf("%s needs layout %q but _layout.%s.html not found", g.URL, g.Layout, g.Layout)
+			return res
 		}
 
-		if err := renderToFile(tmpl, data, outPath); err != nil {
-			return fmt.Errorf("writing %s: %w", outPath, err)
+		relOut, _ := filepath.Rel(dst, outPath)
+		res.entry = cacheEntry{
+			Source: digest([]byte(g.URL)),
+			Layout: layouts.digests[g.Layout],
+			Meta:   pagesDigest, // A listing reads every listed page's metadata
+			Output: relOut,
 		}
-		fmt.Printf("  %s → %s\n", page.RelPath, outPath)
+		if prev, ok := prevCache.lookup(siteDigest, g.URL, res.entry, src, dst); ok {
+			res.entry = prev
+			res.cached = true
+			return res
+		}
+
+		data := siteTemplateData(prefixedPath)
+		data.Title = g.Title
+		data.Taxonomy = g.Taxonomy
+		data.Term = g.Term
+		data.Paginator = g.Paginator
+		tmpls, err := templates.get(wikiResolver)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		defer templates.put(tmpls)
+		if err := renderToFile(tmpls.layouts[g.Layout], data, outPath); err != nil {
+			res.err = fmt.Errorf("writing %s: %w", outPath, err)
+			return res
+		}
+		res.log = fmt.Sprintf("  %s → %s\n", g.URL, outPath)
+		return res
+	}
+	err = renderParallel(len(listings), cfg.EffectiveJobs(), func(i int) renderResult {
+		return renderGenerated(listings[i])
+	}, func(i int, res renderResult) {
+		nextCache.Pages[listings[i].URL] = res.entry
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate or remove the static search index (after rendering so page.HTML is populated)
 	if searchEnabled {
 		if err := writeSearchIndex(dst, buildSearchIndex(pages, basePath)); err != nil {
-			return fmt.Errorf("writing search index: %w", err)
+			return nil, fmt.Errorf("writing search index: %w", err)
 		}
 		fmt.Printf("  Generated %s\n", searchIndexFilename)
 	} else {
 		if err := removeSearchIndex(dst); err != nil {
-			return fmt.Errorf("removing search index: %w", err)
+			return nil, fmt.Errorf("removing search index: %w", err)
 		}
 		fmt.Printf("  Search disabled (%s skipped)\n", searchIndexFilename)
 	}
@@ -242,12 +693,12 @@ func Build(src, dst string, cfg Config) error {
 	if cfg.FeedEnabled() {
 		feed := buildFeed(pages, cfg)
 		if err := writeFeed(dst, feed); err != nil {
-			return fmt.Errorf("writing feed: %w", err)
+			return nil, fmt.Errorf("writing feed: %w", err)
 		}
 		fmt.Printf("  Generated %s\n", feedFilename)
 	} else {
 		if err := removeFeed(dst); err != nil {
-			return fmt.Errorf("removing feed: %w", err)
+			return nil, fmt.Errorf("removing feed: %w", err)
 		}
 	}
 
@@ -256,13 +707,172 @@ func Build(src, dst string, cfg Config) error {
 	staticDst := filepath.Join(dst, "_static")
 	if info, err := os.Stat(staticSrc); err == nil && info.IsDir() {
 		if err := copyDir(staticSrc, staticDst); err != nil {
-			return fmt.Errorf("copying _static: %w", err)
+			return nil, fmt.Errorf("copying _static: %w", err)
 		}
 		fmt.Printf("  Copied _static/\n")
 	}
 
-	fmt.Printf("Built %d pages → %s\n", len(pages), dst)
-	return nil
+	// Persist the build cache, or drop a stale one when caching is off
+	if cfg.CacheEnabled() {
+		if err := removeStaleOutputs(dst, prevCache, nextCache); err != nil {
+			return nil, fmt.Errorf("removing stale outputs: %w", err)
+		}
+		if err := writeCache(dst, nextCache); err != nil {
+			return nil, fmt.Errorf("writing build cache: %w", err)
+		}
+	} else if err := removeCache(dst); err != nil {
+		return nil, err
+	}
+
+	if unchanged > 0 {
+		fmt.Printf("Built %d pages (%d unchanged) → %s\n", len(pages), unchanged, dst)
+	} else {
+		fmt.Printf("Built %d pages → %s\n", len(pages), dst)
+	}
+	return &siteBuild{Src: src, Dst: dst, BasePath: basePath, Pages: pages, Drafts: drafts}, nil
+}
+
+// renderResult is the outcome of rendering one page on a worker.
+type renderResult struct {
+	log    string // Log lines, replayed in page order
+	html   []byte
+	entry  cacheEntry
+	cached bool
+	err    error
+}
+
+// renderParallel calls render for indexes 0..n-1 on up to jobs goroutines,
+// then hands each result to collect strictly in index order, printing its
+// buffered log first. It stops at the lowest-indexed error and returns it,
+// never starting work past that index, so callers observe the same log
+// output and error as a sequential loop would produce.
+func renderParallel(n, jobs int, render func(i int) renderResult, collect func(i int, res renderResult)) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+
+	results := make([]renderResult, n)
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var next atomic.Int64
+	var failedAt atomic.Int64 // lowest index that failed so far
+	failedAt.Store(int64(n))
+
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if int64(i) > failedAt.Load() {
+					close(done[i]) // Past an earlier failure; never reported
+					continue
+				}
+				results[i] = render(i)
+				if results[i].err != nil {
+					for {
+						cur := failedAt.Load()
+						if int64(i) >= cur || failedAt.CompareAndSwap(cur, int64(i)) {
+							break
+						}
+					}
+				}
+				close(done[i])
+			}
+		}()
+	}
+
+	var firstErr error
+	for i := 0; i < n; i++ {
+		<-done[i]
+		res := &results[i]
+		os.Stdout.WriteString(res.log)
+		if res.err != nil {
+			firstErr = res.err
+			break
+		}
+		collect(i, *res)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// checkURLCollisions reports every output path claimed more than once,
+// whether by two pages or by a page and a generated file. Generated names
+// ending in "/" claim a whole directory (e.g. "_static/").
+func checkURLCollisions(pages []Page, generated []string) error {
+	claims := make(map[string][]string)
+	var order []string
+	claim := func(key, by string) {
+		if _, ok := claims[key]; !ok {
+			order = append(order, key)
+		}
+		claims[key] = append(claims[key], by)
+	}
+
+	for _, name := range generated {
+		claim(strings.Trim(name, "/"), name+" (generated)")
+	}
+	for _, page := range pages {
+		u := pageURL(page)
+		by := filepath.ToSlash(page.RelPath)
+		if page.Frontmatter.URL != "" {
+			by += fmt.Sprintf(" (url: %s)", page.Frontmatter.URL)
+		}
+		key := strings.Trim(u, "/")
+		// A page inside a generated directory collides with the directory
+		for _, name := range generated {
+			dir := strings.Trim(name, "/")
+			if strings.HasSuffix(name, "/") && strings.HasPrefix(key, dir+"/") {
+				key = dir
+			}
+		}
+		claim(key, by)
+	}
+
+	var report []string
+	for _, key := range order {
+		if by := claims[key]; len(by) > 1 {
+			report = append(report, fmt.Sprintf("  /%s is claimed by:\n    %s", key, strings.Join(by, "\n    ")))
+		}
+	}
+	if len(report) == 0 {
+		return nil
+	}
+	sort.Strings(report)
+	return fmt.Errorf("%d URL collision(s):\n%s", len(report), strings.Join(report, "\n"))
+}
+
+// sectionLayout returns the variant named after the nearest directory of
+// relPath that has one, without number prefixes: pages in 03-changelog/
+// use _layout.changelog.html if it exists. Returns "" for the base layout.
+// Built-in variants such as landing only apply when a page asks for them,
+// and the taxonomy and term layouts are only for generated pages.
+func sectionLayout(layouts *layoutSet, relPath string) string {
+	dir := filepath.Dir(relPath)
+	for dir != "." {
+		name := reNumPrefix.ReplaceAllString(filepath.Base(dir), "")
+		if name == layoutTaxonomy || name == layoutTerm || layouts.builtin[name] {
+			dir = filepath.Dir(dir)
+			continue
+		}
+		if _, ok := layouts.get(name); ok {
+			return name
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ""
 }
 
 // outputPathFromURL converts a URL path like "/guide/agents/" to a file path.
@@ -307,6 +917,9 @@ func buildFooter(cfg Config) (template.HTML, error) {
 	return "", nil
 }
 
+// copyDir mirrors src into dst. Files whose size and modification time
+// already match the destination copy are skipped, so unchanged static
+// assets aren't rewritten on every build.
 func copyDir(src, dst string) error {
 	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -319,14 +932,43 @@ func copyDir(src, dst string) error {
 			return os.MkdirAll(dstPath, 0o755)
 		}
 
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if existing, err := os.Stat(dstPath); err == nil && existing.Size() == info.Size() && existing.ModTime().Equal(info.ModTime()) {
+			return nil
+		}
+
 		data, err := os.ReadFile(path)
 		if err != nil {
 			return err
 		}
-		return os.WriteFile(dstPath, data, 0o644)
+		if err := os.WriteFile(dstPath, data, 0o644); err != nil {
+			return err
+		}
+		return os.Chtimes(dstPath, info.ModTime(), info.ModTime())
 	})
 }
 
+// parseDates sets DateTime and LastmodTime from the frontmatter, reading
+// dates without an offset in loc.
+func (p *Page) parseDates(loc *time.Location) {
+	p.DateTime, _ = parseDateIn(p.Frontmatter.Date, loc)
+	p.LastmodTime = p.DateTime
+	if t, ok := parseDateIn(p.Frontmatter.Lastmod, loc); ok {
+		p.LastmodTime = t
+	}
+}
+
+// pageLastmod returns a page's raw lastmod, falling back to its date.
+func pageLastmod(p Page) string {
+	if p.Frontmatter.Lastmod != "" {
+		return p.Frontmatter.Lastmod
+	}
+	return p.Frontmatter.Date
+}
+
 // buildPageMeta creates a sorted list of PageMeta from all pages.
 // Root index.md is excluded (matches nav behavior).
 // Pages with dates sort reverse-chronologically first, then undated pages alphabetically.
@@ -337,32 +979,29 @@ func buildPageMeta(pages []Page, basePath string) []PageMeta {
 			continue
 		}
 		title := pageTitle(p)
-		section := ""
-		dir := filepath.Dir(p.RelPath)
-		if dir != "." {
-			parts := strings.SplitN(dir, string(filepath.Separator), 2)
-			section = reNumPrefix.ReplaceAllString(parts[0], "")
-		}
+		section, sectionPath := pageSection(p.RelPath)
 		metas = append(metas, PageMeta{
 			Title:       title,
 			Description: p.Frontmatter.Description,
 			URL:         basePath + pageURL(p),
 			Date:        p.Frontmatter.Date,
+			DateTime:    p.DateTime,
+			Lastmod:     pageLastmod(p),
+			LastmodTime: p.LastmodTime,
 			Extra:       p.Frontmatter.Extra,
 			Section:     section,
+			SectionPath: sectionPath,
 		})
 	}
 
-	sort.Slice(metas, func(i, j int) bool {
+	sort.SliceStable(metas, func(i, j int) bool {
 		// Dated pages first, reverse chronological
-		if metas[i].Date != "" && metas[j].Date != "" {
-			return metas[i].Date > metas[j].Date
+		di, dj := metas[i].DateTime, metas[j].DateTime
+		if di.IsZero() != dj.IsZero() {
+			return !di.IsZero()
 		}
-		if metas[i].Date != "" {
-			return true
-		}
-		if metas[j].Date != "" {
-			return false
+		if !di.Equal(dj) {
+			return di.After(dj)
 		}
 		return metas[i].Title < metas[j].Title
 	})
@@ -370,6 +1009,22 @@ func buildPageMeta(pages []Page, basePath string) []PageMeta {
 	return metas
 }
 
+// pageSection returns the top-level section and full section path of the
+// page at relPath, without number prefixes; both are empty for root pages.
+func pageSection(relPath string) (section, sectionPath string) {
+	dir := filepath.Dir(relPath)
+	if dir == "." {
+		return "", ""
+	}
+	parts := strings.Split(dir, string(filepath.Separator))
+	for i, part := range parts {
+		parts[i] = reNumPrefix.ReplaceAllString(part, "")
+	}
+	return parts[0], strings.Join(parts, "/")
+}
+
+const syntaxCSSFilename = "_syntax.css"
+
 // writeSyntaxCSS generates a combined light/dark syntax highlighting stylesheet.
 func writeSyntaxCSS(dst string, hl HighlightConfig) error {
 	lightName := hl.Light
@@ -381,23 +1036,14 @@ func writeSyntaxCSS(dst string, hl HighlightConfig) error {
 		darkName = "github-dark"
 	}
 
-	path := filepath.Join(dst, "_syntax.css")
-	if err := os.MkdirAll(dst, 0o755); err != nil {
-		return err
-	}
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
+	var buf bytes.Buffer
 	formatter := chromahtml.New(chromahtml.WithClasses(true))
 
 	lightStyle := styles.Get(lightName)
 	if lightStyle == nil {
 		lightStyle = styles.Fallback
 	}
-	if err := formatter.WriteCSS(f, lightStyle); err != nil {
+	if err := formatter.WriteCSS(&buf, lightStyle); err != nil {
 		return err
 	}
 
@@ -405,29 +1051,36 @@ func writeSyntaxCSS(dst string, hl HighlightConfig) error {
 	if darkStyle == nil {
 		darkStyle = styles.Fallback
 	}
-	f.WriteString("\n/* Dark theme */\n")
-	f.WriteString("[data-theme=\"dark\"] {\n")
+	buf.WriteString("\n/* Dark theme */\n")
+	buf.WriteString("[data-theme=\"dark\"] {\n")
 	// Reset all chroma token colors to inherit from the dark base color.
 	// This ensures tokens styled in the light theme but absent from the
 	// dark theme (e.g. NameOther, Punctuation) don't keep their light colors.
-	f.WriteString("  .chroma span { color: inherit; }\n")
-	if err := writeScopedCSS(f, formatter, darkStyle); err != nil {
+	buf.WriteString("  .chroma span { color: inherit; }\n")
+	if err := writeScopedCSS(&buf, formatter, darkStyle); err != nil {
 		return err
 	}
-	f.WriteString("}\n")
+	buf.WriteString("}\n")
 
-	fmt.Printf("  Generated _syntax.css (light: %s, dark: %s)\n", lightName, darkName)
+	// Skip the write when themes are unchanged to keep the file's mtime stable
+	written, err := writeFileIfChanged(filepath.Join(dst, syntaxCSSFilename), buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if written {
+		fmt.Printf("  Generated %s (light: %s, dark: %s)\n", syntaxCSSFilename, lightName, darkName)
+	}
 	return nil
 }
 
-func writeScopedCSS(f *os.File, formatter *chromahtml.Formatter, style *chroma.Style) error {
+func writeScopedCSS(w io.Writer, formatter *chromahtml.Formatter, style *chroma.Style) error {
 	var buf strings.Builder
 	if err := formatter.WriteCSS(&buf, style); err != nil {
 		return err
 	}
 	for _, line := range strings.Split(buf.String(), "\n") {
 		if strings.TrimSpace(line) != "" {
-			f.WriteString("  " + line + "\n")
+			io.WriteString(w, "  "+line+"\n")
 		}
 	}
 	return nil
@@ -1,14 +1,29 @@
 package main
 
-import "testing"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withDates parses the frontmatter dates of test pages, as the build does.
+func withDates(pages []Page) []Page {
+	for i := range pages {
+		pages[i].parseDates(time.UTC)
+	}
+	return pages
+}
 
 func TestBuildPageMetaSortOrder(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "about.md", Frontmatter: Frontmatter{Title: "About"}},
 		{RelPath: "posts/older.md", Frontmatter: Frontmatter{Title: "Older", Date: "2026-01-01"}},
 		{RelPath: "posts/newer.md", Frontmatter: Frontmatter{Title: "Newer", Date: "2026-03-18"}},
 		{RelPath: "zebra.md", Frontmatter: Frontmatter{Title: "Zebra"}},
-	}
+	})
 
 	metas := buildPageMeta(pages, "")
 
@@ -24,6 +39,31 @@ func TestBuildPageMetaSortOrder(t *testing.T) {
 	}
 }
 
+func TestBuildPageMetaSortsByParsedTime(t *testing.T) {
+	// As strings, "2026-03-18T08:00:00Z" sorts after "2026-03-18 09:00"
+	pages := withDates([]Page{
+		{RelPath: "a.md", Frontmatter: Frontmatter{Title: "Midnight", Date: "2026-03-18"}},
+		{RelPath: "b.md", Frontmatter: Frontmatter{Title: "Eight", Date: "2026-03-18T08:00:00Z"}},
+		{RelPath: "c.md", Frontmatter: Frontmatter{Title: "Nine", Date: "2026-03-18 09:00"}},
+		{RelPath: "d.md", Frontmatter: Frontmatter{Title: "Edited", Date: "2026-01-01", Lastmod: "2026-03-20"}},
+	})
+
+	metas := buildPageMeta(pages, "")
+	var titles []string
+	for _, m := range metas {
+		titles = append(titles, m.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Nine,Eight,Midnight,Edited" {
+		t.Errorf("order = %s, want Nine,Eight,Midnight,Edited", got)
+	}
+	if m := metas[0]; m.Lastmod != "2026-03-18 09:00" || !m.LastmodTime.Equal(m.DateTime) {
+		t.Errorf("lastmod = %q, %v; want the date", m.Lastmod, m.LastmodTime)
+	}
+	if m := metas[3]; m.Lastmod != "2026-03-20" || m.LastmodTime.Format("2006-01-02") != "2026-03-20" {
+		t.Errorf("lastmod = %q, %v; want 2026-03-20", m.Lastmod, m.LastmodTime)
+	}
+}
+
 func TestBuildPageMetaSection(t *testing.T) {
 	pages := []Page{
 		{RelPath: "index.md", Frontmatter: Frontmatter{Title: "Home"}},
@@ -60,3 +100,491 @@ func TestBuildPageMetaSection(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildPageMetaSectionPath(t *testing.T) {
+	pages := []Page{
+		{RelPath: filepath.Join("01-guide", "02-advanced", "01-caching.md"), Frontmatter: Frontmatter{Title: "Caching"}},
+	}
+
+	metas := buildPageMeta(pages, "")
+	if metas[0].Section != "guide" {
+		t.Errorf("Section = %q, want guide", metas[0].Section)
+	}
+	if metas[0].SectionPath != "guide/advanced" {
+		t.Errorf("SectionPath = %q, want guide/advanced", metas[0].SectionPath)
+	}
+}
+
+func TestRenderParallelReportsFirstErrorInOrder(t *testing.T) {
+	var collected []int
+	err := renderParallel(20, 8, func(i int) renderResult {
+		if i == 7 || i == 13 {
+			return renderResult{err: fmt.Errorf("page %d failed", i)}
+		}
+		return renderResult{}
+	}, func(i int, res renderResult) {
+		collected = append(collected, i)
+	})
+
+	if err == nil || err.Error() != "page 7 failed" {
+		t.Fatalf("expected first error from page 7, got %v", err)
+	}
+	if len(collected) != 7 {
+		t.Fatalf("expected pages 0-6 collected before the error, got %v", collected)
+	}
+	for i, idx := range collected {
+		if idx != i {
+			t.Fatalf("expected results collected in order, got %v", collected)
+		}
+	}
+}
+
+func TestBuildParallelMatchesSequential(t *testing.T) {
+	src := t.TempDir()
+	for i := 0; i < 12; i++ {
+		writeTestFile(t, filepath.Join(src, "01-guide", fmt.Sprintf("%02d-page-%d.md", i, i)),
+			fmt.Sprintf("# Page %d\n\nSee [[Page 0]].\n", i))
+	}
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "note.html"), `<div class="note">{{ .Inner }}</div>`)
+	writeTestFile(t, filepath.Join(src, "index.md"), "{{< note >}}**hi**{{< /note >}}\n")
+
+	seq := t.TempDir()
+	par := t.TempDir()
+	if err := Build(src, seq, Config{Jobs: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(src, par, Config{Jobs: 6}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := filepath.WalkDir(seq, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(seq, path)
+		a := readTestFile(t, path)
+		b := readTestFile(t, filepath.Join(par, rel))
+		if a != b {
+			t.Errorf("%s differs between sequential and parallel builds", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildFailsOnURLCollision(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "01-guide", "01-intro.md"), "# Intro\n")
+	writeTestFile(t, filepath.Join(src, "01-guide", "intro.md"), "# Also intro\n")
+	writeTestFile(t, filepath.Join(src, "about.md"), "---\nurl: /guide/intro\n---\n")
+	writeTestFile(t, filepath.Join(src, "search.md"), "---\nurl: _search.json\n---\n")
+	writeTestFile(t, filepath.Join(src, "logo.md"), "---\nurl: /_static/logo/\n---\n")
+	writeTestFile(t, filepath.Join(src, "_static", "logo.svg"), "<svg/>")
+
+	err := Build(src, t.TempDir(), Config{})
+	if err == nil {
+		t.Fatal("expected a URL collision error")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"3 URL collision(s)",
+		"/guide/intro is claimed by:\n    01-guide/01-intro.md\n    01-guide/intro.md\n    about.md (url: /guide/intro)",
+		"/_search.json is claimed by:\n    _search.json (generated)\n    search.md (url: _search.json)",
+		"/_static is claimed by:\n    _static/ (generated)\n    logo.md (url: /_static/logo/)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestBuildUsesNavFile(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "01-guide", "01-intro.md"), "# Intro\n")
+	writeTestFile(t, filepath.Join(src, "other.md"), "# Other\n")
+	writeTestFile(t, filepath.Join(src, navFilename), "- title: Start here\n  children:\n    - page: 01-guide/01-intro.md\n")
+
+	dst := t.TempDir()
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	html := readTestFile(t, filepath.Join(dst, "other", "index.html"))
+	if !strings.Contains(html, "<summary>Start here</summary>") {
+		t.Error("expected sidebar from _nav.yaml")
+	}
+	if strings.Contains(html, `href="/other/"`) {
+		t.Error("orphan page should not appear in an explicit nav")
+	}
+
+	if err := Build(src, t.TempDir(), Config{Nav: []NavEntry{{Page: "other.md"}}}); err == nil {
+		t.Error("expected an error when both _nav.yaml and [[nav]] are set")
+	}
+}
+
+func TestBuildRendersBreadcrumbsAndPager(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(src, "01-guide", "01-intro.md"), "---\ntitle: Intro\n---\n")
+	writeTestFile(t, filepath.Join(src, "01-guide", "02-setup.md"), "---\ntitle: Setup\n---\n")
+	writeTestFile(t, filepath.Join(src, "01-guide", "03-deploy.md"), "---\ntitle: Deploy\n---\n")
+
+	dst := t.TempDir()
+	if err := Build(src, dst, Config{BasePath: "/site"}); err != nil {
+		t.Fatal(err)
+	}
+
+	html := readTestFile(t, filepath.Join(dst, "guide", "setup", "index.html"))
+	for _, want := range []string{
+		`<li>Guide</li>`,
+		`<li><span aria-current="page">Setup</span></li>`,
+		`<a href="/site/guide/intro/" rel="prev">`,
+		`<a href="/site/guide/deploy/" rel="next">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("setup page missing %q", want)
+		}
+	}
+
+	first := readTestFile(t, filepath.Join(dst, "guide", "intro", "index.html"))
+	if strings.Contains(first, `rel="prev"`) {
+		t.Error("first page should have no previous link")
+	}
+}
+
+func TestBuildUsesPartials(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_partials", "footer.html"), `<footer class="custom">{{ .SiteName }} footer</footer>`)
+	writeTestFile(t, filepath.Join(src, "_partials", "blog", "byline.html"), `<p class="byline">by {{ .Get "author" }}</p>`)
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "byline.html"), `{{ template "partials/blog/byline" . }}`)
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n\n{{< byline author=\"Ada\" />}}\n")
+
+	if err := Build(src, dst, Config{SiteName: "Demo"}); err != nil {
+		t.Fatal(err)
+	}
+	out := readTestFile(t, filepath.Join(dst, "index.html"))
+	for _, want := range []string{
+		`<footer class="custom">Demo footer</footer>`, // Overridden partial
+		`<nav data-topnav>`,                           // Built-in partial still used
+		`<p class="byline">by Ada</p>`,                // Partial called from a shortcode
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s", want)
+		}
+	}
+}
+
+func TestBuildCascadesFrontmatterDefaults(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.html"), `{{ block "content" . }}base {{ .Extra.kind }} {{ .Extra.badge }}{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "_layout.post.html"), `{{ define "content" }}post {{ .Extra.kind }} {{ .Extra.badge }}{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "_layout.changelog.html"), `{{ define "content" }}changelog{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "blog", "_defaults.yaml"), "layout: post\nkind: article\nbadge: blog\n")
+	writeTestFile(t, filepath.Join(src, "blog", "index.md"), "---\ntitle: Blog\ncascade:\n  badge: cascaded\n---\n")
+	writeTestFile(t, filepath.Join(src, "blog", "first.md"), "# First\n")
+	writeTestFile(t, filepath.Join(src, "blog", "2026", "second.md"), "---\nkind: essay\n---\n")
+	writeTestFile(t, filepath.Join(src, "blog", "own.md"), "---\nlayout: \"\"\n---\n")
+	writeTestFile(t, filepath.Join(src, "03-changelog", "v1.md"), "# v1\n")
+	writeTestFile(t, filepath.Join(src, "03-changelog", "v2.md"), "---\nlayout: post\n---\n")
+	writeTestFile(t, filepath.Join(src, "landing", "intro.md"), "# Intro\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ page, want string }{
+		{"blog/index.html", "post article blog"},           // _defaults.yaml, not its own cascade
+		{"blog/first/index.html", "post article cascaded"}, // cascade wins over _defaults.yaml
+		{"blog/2026/second/index.html", "post essay cascaded"},
+		{"blog/own/index.html", "base article cascaded"}, // Page frontmatter wins
+		{"changelog/v1/index.html", "changelog"},         // Section-named variant
+		{"changelog/v2/index.html", "post  "},
+		{"landing/intro/index.html", "base  "}, // Built-in variants need layout: landing
+	}
+	for _, tt := range tests {
+		if got := readTestFile(t, filepath.Join(dst, tt.page)); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestBuildExposesData(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_data", "team.yaml"), "- name: Ada\n- name: Lin\n")
+	writeTestFile(t, filepath.Join(src, "_layout.html"), `{{ len .Data.team }}|{{ .Content }}`)
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "team.html"), `{{ range .Page.Data.team }}{{ .name }};{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "index.md"), "{{< team />}}\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dst, "index.html")); !strings.HasPrefix(got, "2|") || !strings.Contains(got, "Ada;Lin;") {
+		t.Errorf("output = %q, want team from _data in layout and shortcode", got)
+	}
+
+	writeTestFile(t, filepath.Join(src, "_data", "team.yaml"), "- name: [\n")
+	if err := Build(src, dst, Config{}); err == nil || !strings.HasPrefix(err.Error(), filepath.Join("_data", "team.yaml")+":") {
+		t.Errorf("got %v, want error naming _data/team.yaml", err)
+	}
+}
+
+func TestBuildReportsAllFrontmatterErrors(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A\ndraft: maybe\n---\n")
+	writeTestFile(t, filepath.Join(src, "guide", "b.md"), "---\ntitle: B\nweight: heavy\n---\n")
+	writeTestFile(t, filepath.Join(src, "blog", "_defaults.yaml"), "layout: post\ntoc: [\n")
+
+	err := Build(src, dst, Config{})
+	if err == nil {
+		t.Fatal("expected build to fail on invalid frontmatter")
+	}
+	for _, want := range []string{"a.md:3: ", filepath.Join("guide", "b.md") + ":3: ", filepath.Join("blog", "_defaults.yaml") + ":"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+
+	// lenient builds anyway, with whatever frontmatter parsed
+	if err := Build(src, dst, Config{Lenient: true}); err != nil {
+		t.Fatalf("lenient build failed: %v", err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "guide", "b", "index.html")), "<title>B") {
+		t.Error("expected lenient build to keep the valid title")
+	}
+}
+
+func TestBuildLenientKeepsInheritedDefaults(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.html"), `{{ .Extra.kind }}`)
+	writeTestFile(t, filepath.Join(src, "_defaults.yaml"), "kind: doc\n")
+	writeTestFile(t, filepath.Join(src, "blog", "_defaults.yaml"), "kind: [\n")
+	writeTestFile(t, filepath.Join(src, "blog", "2026", "post.md"), "# Post\n")
+
+	if err := Build(src, dst, Config{Lenient: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dst, "blog", "2026", "post", "index.html")); got != "doc" {
+		t.Errorf("got %q, want the root defaults past the broken _defaults.yaml", got)
+	}
+}
+
+func TestBuildReportsInvalidCascade(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "blog", "index.md"), "---\ntitle: Blog\ncascade:\n  draft: sometimes\n---\n")
+	writeTestFile(t, filepath.Join(src, "blog", "post.md"), "# Post\n")
+
+	err := Build(src, dst, Config{})
+	if err == nil || !strings.Contains(err.Error(), filepath.Join("blog", "index.md")+":4: ") {
+		t.Errorf("got %v, want error at blog/index.md:4", err)
+	}
+}
+
+func TestBuildSkipsUnpublishedPages(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n\nSee [[Draft]], [[Later]], and [[Gone]].\n")
+	writeTestFile(t, filepath.Join(src, "draft.md"), "---\ntitle: Draft\ndraft: true\n---\n")
+	writeTestFile(t, filepath.Join(src, "later.md"), "---\ntitle: Later\npublish_date: 2999-01-01\n---\n")
+	writeTestFile(t, filepath.Join(src, "dated.md"), "---\ntitle: Dated\ndate: 2999-01-01\n---\n")
+	writeTestFile(t, filepath.Join(src, "gone.md"), "---\ntitle: Gone\nexpiry_date: 2000-01-01\n---\n")
+	writeTestFile(t, filepath.Join(src, "live.md"), "---\ntitle: Live\ndate: 2026-01-01\npublish_date: 2000-01-01\nexpiry_date: 2999-01-01\n---\n")
+	cfg := Config{Feed: FeedConfig{Enabled: boolPtr(true), Link: "https://example.com"}}
+
+	dst := t.TempDir()
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"draft", "later", "dated", "gone"} {
+		if _, err := os.Stat(filepath.Join(dst, name, "index.html")); !os.IsNotExist(err) {
+			t.Errorf("%s was built, want it skipped", name)
+		}
+	}
+	home := readTestFile(t, filepath.Join(dst, "index.html"))
+	search := readTestFile(t, filepath.Join(dst, searchIndexFilename))
+	feed := readTestFile(t, filepath.Join(dst, feedFilename))
+	for _, title := range []string{"Draft", "Later", "Dated", "Gone"} {
+		if strings.Contains(home, ">"+title+"<") || strings.Contains(search, `"`+title+`"`) || strings.Contains(feed, title) {
+			t.Errorf("%s appears in the nav, a wikilink, search, or the feed", title)
+		}
+	}
+	if !strings.Contains(feed, "Live") || strings.Contains(home, "page-status") {
+		t.Error("expected Live in the feed and no status banner")
+	}
+
+	dst = t.TempDir()
+	cfg.Drafts, cfg.Future, cfg.Expired = true, true, true
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"draft": "<strong>Draft</strong>",
+		"later": "publishes on January 1, 2999",
+		"dated": "publishes on January 1, 2999",
+		"gone":  "expired on January 1, 2000",
+	} {
+		if got := readTestFile(t, filepath.Join(dst, name, "index.html")); !strings.Contains(got, want) {
+			t.Errorf("%s: missing banner %q", name, want)
+		}
+	}
+	if home := readTestFile(t, filepath.Join(dst, "index.html")); !strings.Contains(home, `href="/later/"`) {
+		t.Error("expected the wikilink to a scheduled page to resolve with --future")
+	}
+}
+
+func TestBuildFlagsOnlyIncludeTheirOwnStatus(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "both.md"), "---\ndraft: true\npublish_date: 2999-01-01\n---\n")
+
+	if err := Build(src, dst, Config{Drafts: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "both", "index.html")); !os.IsNotExist(err) {
+		t.Error("a scheduled draft needs --future as well as --drafts")
+	}
+}
+
+func TestBuildTimezone(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.html"),
+		`{{ .Date }}|{{ formatDate .DateTime "2006-01-02 15:04 MST" }}|{{ formatDate .Lastmod "Monday 2 January" "de" }}{{ .Content }}`)
+	writeTestFile(t, filepath.Join(src, "posts/local.md"), "---\ndate: 2026-03-18 09:00\n---\n")
+	writeTestFile(t, filepath.Join(src, "posts/offset.md"), "---\ndate: 2026-03-18T20:00:00Z\nlastmod: 2026-03-19T23:30:00-05:00\n---\n")
+	cfg := Config{
+		Timezone: "Asia/Tokyo",
+		Feed:     FeedConfig{Enabled: boolPtr(true), Link: "https://example.com"},
+	}
+
+	dst := t.TempDir()
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"local":  "2026-03-18 09:00|2026-03-18 09:00 JST|Mittwoch 18 März",
+		"offset": "2026-03-18T20:00:00Z|2026-03-19 05:00 JST|Freitag 20 März",
+	} {
+		if got := readTestFile(t, filepath.Join(dst, "posts", name, "index.html")); !strings.HasPrefix(got, want) {
+			t.Errorf("%s: got %q, want prefix %q", name, got, want)
+		}
+	}
+	feed := readTestFile(t, filepath.Join(dst, feedFilename))
+	if !strings.Contains(feed, "<pubDate>Wed, 18 Mar 2026 09:00:00 +0900</pubDate>") ||
+		strings.Index(feed, "/posts/offset/") > strings.Index(feed, "/posts/local/") {
+		t.Errorf("expected Tokyo pubDates, offset page first:\n%s", feed)
+	}
+
+	// Without a timezone, dates are UTC wherever the build runs
+	cfg.Timezone = ""
+	dst = t.TempDir()
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dst, "posts", "local", "index.html")); !strings.HasPrefix(got, "2026-03-18 09:00|2026-03-18 09:00 UTC|") {
+		t.Errorf("local: got %q, want UTC", got)
+	}
+
+	cfg.Timezone = "Mars/Olympus_Mons"
+	if err := Build(src, t.TempDir(), cfg); err == nil || !strings.Contains(err.Error(), `invalid timezone "Mars/Olympus_Mons"`) {
+		t.Errorf("err = %v, want invalid timezone", err)
+	}
+}
+
+func TestBuildTaxonomyPages(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(src, "blog/first.md"), "---\ntitle: First\ndate: 2026-01-01\ntags: [go, Release Notes]\n---\n")
+	writeTestFile(t, filepath.Join(src, "blog/second.md"), "---\ntitle: Second\ndate: 2026-02-01\ntags: Go\n---\n")
+	writeTestFile(t, filepath.Join(src, "term/page.md"), "# In a section named term\n")
+	cfg := Config{Taxonomies: map[string]string{"tags": "tags"}}
+
+	dst := t.TempDir()
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	index := readTestFile(t, filepath.Join(dst, "tags", "index.html"))
+	// Terms are named as first written in .Pages order, newest first,
+	// everywhere they're shown
+	if !strings.Contains(index, `<a href="/tags/go/">Go</a> <small class="text-light">2</small>`) ||
+		!strings.Contains(index, `<a href="/tags/release-notes/">Release Notes</a>`) {
+		t.Errorf("tags index doesn't list the terms:\n%s", index)
+	}
+	term := readTestFile(t, filepath.Join(dst, "tags", "go", "index.html"))
+	if !strings.Contains(term, "<title>Tags: Go") || strings.Index(term, "Second") > strings.Index(term, "First") {
+		t.Errorf("go term page doesn't list Second then First:\n%s", term)
+	}
+	first := readTestFile(t, filepath.Join(dst, "blog", "first", "index.html"))
+	if !strings.Contains(first, `<a href="/tags/go/">Go</a>, <a href="/tags/release-notes/">Release Notes</a>`) {
+		t.Errorf("page doesn't link its tags:\n%s", first)
+	}
+	if page := readTestFile(t, filepath.Join(dst, "term", "page", "index.html")); !strings.Contains(page, "In a section named term") {
+		t.Error("a section named term should keep the default layout")
+	}
+
+	// A term nobody uses anymore loses its page
+	writeTestFile(t, filepath.Join(src, "blog/first.md"), "---\ntitle: First\ndate: 2026-01-01\ntags: [Go]\n---\n")
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "tags", "release-notes")); !os.IsNotExist(err) {
+		t.Error("stale term page was not removed")
+	}
+
+	// A page can't take the taxonomy's URL
+	writeTestFile(t, filepath.Join(src, "tags/index.md"), "# Tags\n")
+	if err := Build(src, t.TempDir(), cfg); err == nil || !strings.Contains(err.Error(), "tags/ (generated)") {
+		t.Errorf("err = %v, want a URL collision with the generated tags/", err)
+	}
+}
+
+func TestBuildPaginatesSections(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.html"),
+		`{{ .CurrentPath }} {{ with .Paginator }}{{ .PageNumber }}/{{ .TotalPages }}:{{ range .Items }}{{ .Title }},{{ end }}|{{ .Prev }}|{{ .Next }}{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "blog/index.md"), "---\ntitle: Blog\npaginate: 2\ntags: [go]\n---\n")
+	for i, name := range []string{"one", "two", "three", "four", "five"} {
+		writeTestFile(t, filepath.Join(src, "blog", name+".md"), fmt.Sprintf("---\ntitle: %s\ndate: 2026-01-0%d\ntags: [go]\n---\n", name, i+1))
+	}
+	writeTestFile(t, filepath.Join(src, "about.md"), "# About\n")
+	cfg := Config{Taxonomies: map[string]string{"tags": "tags"}, Paginate: 4}
+
+	dst := t.TempDir()
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"blog":           "/blog/ 1/3:five,four,||/blog/page/2/",
+		"blog/page/2":    "/blog/page/2/ 2/3:three,two,|/blog/|/blog/page/3/",
+		"blog/page/3":    "/blog/page/3/ 3/3:one,|/blog/page/2/|",
+		"about":          "/about/ ",
+		"tags/go":        "/tags/go/ 1/2:five,four,three,two,||/tags/go/page/2/",
+		"tags/go/page/2": "/tags/go/page/2/ 2/2:one,Blog,|/tags/go/|",
+	} {
+		if got := readTestFile(t, filepath.Join(dst, path, "index.html")); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+
+	// Fewer posts drop the last page
+	os.Remove(filepath.Join(src, "blog", "five.md"))
+	if err := Build(src, dst, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "blog", "page", "3")); !os.IsNotExist(err) {
+		t.Error("stale /blog/page/3/ was not removed")
+	}
+	if got := readTestFile(t, filepath.Join(dst, "blog", "page", "2", "index.html")); got != "/blog/page/2/ 2/2:two,one,|/blog/|" {
+		t.Errorf("blog/page/2 = %q after removing a post", got)
+	}
+
+	// A page can't sit where a later page of the list goes
+	writeTestFile(t, filepath.Join(src, "blog/page/2.md"), "# Page two\n")
+	if err := Build(src, t.TempDir(), cfg); err == nil || !strings.Contains(err.Error(), "blog/index.md (page 2)") {
+		t.Errorf("err = %v, want a collision with blog/index.md (page 2)", err)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// legacyCacheFilename is where older versions kept the manifest: inside the
+// output directory, where deploys picked it up.
+const legacyCacheFilename = ".moat-cache"
+
+// userCacheDir locates the directory that holds build manifests. Tests
+// point it at a temporary directory.
+var userCacheDir = os.UserCacheDir
+
+// buildCache is the manifest persisted between builds into the same output
+// directory. A page is re-rendered only when its own entry or the site
+// digest differs from the previous build.
+type buildCache struct {
+	Dst     string                `json:"dst"` // Absolute output directory; first, so pruning can read it cheaply
+	Version string                `json:"version"`
+	Site    string                `json:"site"`  // Digest of inputs shared by every page
+	Pages   map[string]cacheEntry `json:"pages"` // Keyed by source RelPath, or URL for generated pages
+}
+
+// cacheEntry records what a page was rendered from and what it produced.
+type cacheEntry struct {
+	Source     string            `json:"source"`               // Digest of the source file
+	Layout     string            `json:"layout"`               // Digest of the layout variant used
+	Shortcodes map[string]string `json:"shortcodes,omitempty"` // Shortcode name → digest, for shortcodes the page calls
+	Files      map[string]string `json:"files,omitempty"`      // Files read by shortcodes (slash path under src) → digest
+	Meta       string            `json:"meta,omitempty"`       // Digest of other pages' metadata the page reads
+	Output     string            `json:"output"`               // Output file, relative to dst
+	HTML       string            `json:"html"`                 // Rendered content, reused by search and feed
+	Unresolved []string          `json:"unresolved,omitempty"` // Unresolved wikilink targets, reused by checks
+}
+
+// sameInputs reports whether two entries were rendered from identical inputs.
+func (e cacheEntry) sameInputs(o cacheEntry) bool {
+	if e.Source != o.Source || e.Layout != o.Layout || e.Output != o.Output || e.Meta != o.Meta || len(e.Shortcodes) != len(o.Shortcodes) {
+		return false
+	}
+	for name, d := range e.Shortcodes {
+		if od, ok := o.Shortcodes[name]; !ok || od != d {
+			return false
+		}
+	}
+	return true
+}
+
+func newBuildCache(site string) *buildCache {
+	return &buildCache{Version: version, Site: site, Pages: make(map[string]cacheEntry)}
+}
+
+// cachePath returns the manifest file for builds into dst. Manifests live in
+// moat/ under the user cache directory (the system temp directory if there
+// is none), one per absolute output path, so they never end up in a deploy.
+func cachePath(dst string) string {
+	return filepath.Join(cacheDir(), digest([]byte(absPath(dst)))[:16]+".json")
+}
+
+// cacheDir is the directory holding every output directory's manifest.
+func cacheDir() string {
+	base, err := userCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "moat")
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// loadCache reads the manifest for dst. A missing, unreadable, or
+// version-mismatched manifest yields an empty cache, forcing a full build.
+func loadCache(dst string) *buildCache {
+	empty := newBuildCache("")
+	data, err := os.ReadFile(cachePath(dst))
+	if err != nil {
+		return empty
+	}
+	var c buildCache
+	if err := json.Unmarshal(data, &c); err != nil || c.Version != version || c.Pages == nil {
+		return empty
+	}
+	return &c
+}
+
+// writeCache saves the manifest for dst, deletes one an older version left
+// in dst itself, and prunes manifests of output directories that are gone.
+func writeCache(dst string, c *buildCache) error {
+	c.Dst = absPath(dst)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshaling build cache: %w", err)
+	}
+	path := cachePath(dst)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dst, legacyCacheFilename)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	pruneCaches()
+	return nil
+}
+
+// pruneCaches removes manifests whose output directory no longer exists, or
+// that don't record one. It is best-effort: a manifest it can't read or
+// remove is left for the next build to try.
+func pruneCaches() {
+	paths, _ := filepath.Glob(filepath.Join(cacheDir(), "*.json"))
+	for _, path := range paths {
+		dst, ok := manifestDst(path)
+		if !ok {
+			continue
+		}
+		if dst != "" {
+			if _, err := os.Stat(dst); !os.IsNotExist(err) {
+				continue
+			}
+		}
+		os.Remove(path)
+	}
+}
+
+// manifestDst reads the output directory recorded in the manifest at path
+// without decoding the pages that follow it. Reports false if the file
+// can't be read or isn't a manifest.
+func manifestDst(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", false
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		if key == "dst" {
+			var dst string
+			if err := dec.Decode(&dst); err != nil {
+				return "", false
+			}
+			return dst, true
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return "", false
+		}
+	}
+	return "", true
+}
+
+func removeCache(dst string) error {
+	for _, path := range []string{cachePath(dst), filepath.Join(dst, legacyCacheFilename)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing build cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// lookup returns the previous entry for relPath if it can be reused as-is:
+// same site digest, same inputs, files read by its shortcodes unchanged
+// under src, and the output file still on disk.
+func (c *buildCache) lookup(site, relPath string, entry cacheEntry, src, dst string) (cacheEntry, bool) {
+	if c.Site != site {
+		return cacheEntry{}, false
+	}
+	prev, ok := c.Pages[relPath]
+	if !ok || !prev.sameInputs(entry) {
+		return cacheEntry{}, false
+	}
+	for rel, d := range prev.Files {
+		data, err := os.ReadFile(filepath.Join(src, filepath.FromSlash(rel)))
+		if err != nil || digest(data) != d {
+			return cacheEntry{}, false
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, prev.Output)); err != nil {
+		return cacheEntry{}, false
+	}
+	return prev, true
+}
+
+// removeStaleOutputs deletes outputs recorded by the previous build that no
+// page produces anymore (e.g. a page was deleted or its URL changed).
+func removeStaleOutputs(dst string, prev, next *buildCache) error {
+	live := make(map[string]bool, len(next.Pages))
+	for _, e := range next.Pages {
+		live[e.Output] = true
+	}
+	var stale []string
+	for _, e := range prev.Pages {
+		if e.Output != "" && !live[e.Output] {
+			stale = append(stale, e.Output)
+		}
+	}
+	sort.Strings(stale)
+	for _, rel := range stale {
+		path := filepath.Join(dst, rel)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// Prune directories left empty, stopping at dst
+		for dir := filepath.Dir(path); dir != dst && len(dir) > len(dst); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+		fmt.Printf("  Removed stale %s\n", rel)
+	}
+	return nil
+}
+
+// digest returns a hex SHA-256 over parts, separated so that
+// ("ab", "c") and ("a", "bc") differ.
+func digest(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// digestJSON digests the JSON encoding of v. Encoding failures fall back to
+// a fresh value each time, which only costs a full rebuild.
+func digestJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return digest([]byte(err.Error()))
+	}
+	return digest(data)
+}
+
+// Shortcode call sites in markdown source: {{< name ... >}} or {{< /name >}}
+var reShortcodeName = regexp.MustCompile(`\{\{<\s*/?\s*(\w+)`)
+
+// shortcodeDeps maps each shortcode name called in source to its digest.
+// Unknown names map to "" so that adding the shortcode later invalidates the page.
+func (reg *shortcodeRegistry) shortcodeDeps(source []byte) map[string]string {
+	var deps map[string]string
+	for _, m := range reShortcodeName.FindAllSubmatch(source, -1) {
+		if deps == nil {
+			deps = make(map[string]string)
+		}
+		name := string(m[1])
+		deps[name] = reg.digests[name]
+	}
+	return deps
+}
+
+// Template source that reads the full page list: .Pages (the site's, a
+// term's, or .Page.Pages in a shortcode) or the SectionPages method
+var reListsPages = regexp.MustCompile(`\.Pages\b|\bSectionPages\b`)
+
+// listsPages reports whether template source reads the full page list, and
+// so every page's metadata rather than just what the site digest covers.
+func listsPages(source []byte) bool {
+	return reListsPages.Match(source)
+}
+
+// anyListsPages reports whether any shortcode in deps reads the full page list.
+func (reg *shortcodeRegistry) anyListsPages(deps map[string]string) bool {
+	for name := range deps {
+		if reg.listsPages[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileIfChanged writes data to path unless the file already holds
+// exactly that content. Reports whether a write happened.
+func writeFileIfChanged(path string, data []byte) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain keeps build manifests out of the real user cache directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "moat-test-cache-")
+	if err != nil {
+		panic(err)
+	}
+	userCacheDir = func() (string, error) { return dir, nil }
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBuildCacheRerendersOnlyChangedPages(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A\n---\nFirst version\n")
+	writeTestFile(t, filepath.Join(src, "b.md"), "---\ntitle: B\n---\nUntouched\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath(dst)); err != nil {
+		t.Fatalf("expected the manifest to be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, legacyCacheFilename)); !os.IsNotExist(err) {
+		t.Errorf("expected no manifest in the output directory, got %v", err)
+	}
+
+	// Mark b's output so we can tell whether it was re-rendered
+	bOut := filepath.Join(dst, "b", "index.html")
+	writeTestFile(t, bOut, readTestFile(t, bOut)+"<!-- marker -->")
+
+	// Body-only edit: only a.md re-renders
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A\n---\nSecond version\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "a", "index.html")), "Second version") {
+		t.Error("expected changed page to be re-rendered")
+	}
+	if !strings.Contains(readTestFile(t, bOut), "<!-- marker -->") {
+		t.Error("expected unchanged page to be skipped")
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, searchIndexFilename)), "Untouched") {
+		t.Error("expected search index to include cached page content")
+	}
+
+	// Title change alters nav and Pages for everyone: full rebuild
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A Renamed\n---\nSecond version\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(readTestFile(t, bOut), "<!-- marker -->") {
+		t.Error("expected title change to re-render every page")
+	}
+}
+
+func TestBuildCacheDescriptionEditSkipsUnrelatedPages(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.list.html"), `{{ define "content" }}{{ range .Pages }}{{ .Description }};{{ end }}{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A\ndescription: First\n---\nA\n")
+	writeTestFile(t, filepath.Join(src, "b.md"), "B\n")
+	writeTestFile(t, filepath.Join(src, "c.md"), "C\n")
+	writeTestFile(t, filepath.Join(src, "d.md"), "D\n")
+	writeTestFile(t, filepath.Join(src, "list.md"), "---\nlayout: list\n---\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	dOut := filepath.Join(dst, "d", "index.html")
+	writeTestFile(t, dOut, readTestFile(t, dOut)+"<!-- marker -->")
+
+	writeTestFile(t, filepath.Join(src, "a.md"), "---\ntitle: A\ndescription: Second\n---\nA\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "a", "index.html")), "Second") {
+		t.Error("expected edited page to be re-rendered")
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "list", "index.html")), "Second;") {
+		t.Error("expected page listing .Pages to be re-rendered")
+	}
+	if !strings.Contains(readTestFile(t, dOut), "<!-- marker -->") {
+		t.Error("expected page that doesn't read the description to be skipped")
+	}
+}
+
+func TestBuildCacheTracksShortcodesAndRemovesStaleOutputs(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "hi.html"), "<b>hi</b>")
+	writeTestFile(t, filepath.Join(src, "uses.md"), "{{< hi />}}\n")
+	writeTestFile(t, filepath.Join(src, "plain.md"), "Plain\n")
+	writeTestFile(t, filepath.Join(src, "gone.md"), "Bye\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	plainOut := filepath.Join(dst, "plain", "index.html")
+	writeTestFile(t, plainOut, readTestFile(t, plainOut)+"<!-- marker -->")
+
+	// Shortcode edit: only pages calling it re-render
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "hi.html"), "<i>hello</i>")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "uses", "index.html")), "<i>hello</i>") {
+		t.Error("expected page using the edited shortcode to re-render")
+	}
+	if !strings.Contains(readTestFile(t, plainOut), "<!-- marker -->") {
+		t.Error("expected page without the shortcode to be skipped")
+	}
+
+	if err := os.Remove(filepath.Join(src, "gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "gone")); !os.IsNotExist(err) {
+		t.Error("expected output of deleted page to be removed")
+	}
+}
+
+func TestBuildCacheTracksIncludedFiles(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_snippets", "intro.md"), "First snippet\n")
+	writeTestFile(t, filepath.Join(src, "uses.md"), "{{< include \"_snippets/intro.md\" />}}\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "_snippets", "intro.md"), "Second snippet\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "uses", "index.html")), "Second snippet") {
+		t.Error("expected page to re-render when an included file changes")
+	}
+}
+
+func TestBuildCacheTracksDefaults(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.wide.html"), `{{ define "content" }}wide{{ end }}`)
+	writeTestFile(t, filepath.Join(src, "blog", "post.md"), "Post\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "blog", "_defaults.yaml"), "layout: wide\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readTestFile(t, filepath.Join(dst, "blog", "post", "index.html")), "wide") {
+		t.Error("expected page to re-render when _defaults.yaml changes")
+	}
+}
+
+func TestBuildCacheTracksData(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_data", "site.yaml"), "version: one\n")
+	writeTestFile(t, filepath.Join(src, "_layout.html"), `{{ .Data.site.version }}`)
+	writeTestFile(t, filepath.Join(src, "a.md"), "A\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(src, "_data", "site.yaml"), "version: two\n")
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dst, "a", "index.html")); got != "two" {
+		t.Errorf("output = %q, want page re-rendered with new data", got)
+	}
+}
+
+func TestBuildCacheDisabled(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "a.md"), "A\n")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dst, "a", "index.html")
+	writeTestFile(t, out, "stale")
+
+	if err := Build(src, dst, Config{Cache: boolPtr(false)}); err != nil {
+		t.Fatal(err)
+	}
+	if readTestFile(t, out) == "stale" {
+		t.Error("expected disabled cache to re-render every page")
+	}
+	if _, err := os.Stat(cachePath(dst)); !os.IsNotExist(err) {
+		t.Error("expected manifest to be removed when cache is disabled")
+	}
+}
+
+func TestBuildCacheMovesLegacyManifest(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(dst, legacyCacheFilename), "{}")
+
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, legacyCacheFilename)); !os.IsNotExist(err) {
+		t.Error("expected the manifest left in the output directory to be removed")
+	}
+	if cachePath(dst) == cachePath(t.TempDir()) {
+		t.Error("expected output directories to have their own manifests")
+	}
+}
+
+func TestBuildCachePrunesManifestsOfRemovedOutputs(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	gone := filepath.Join(t.TempDir(), "gone")
+	kept := t.TempDir()
+
+	if err := Build(src, gone, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(src, kept, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath(gone)); !os.IsNotExist(err) {
+		t.Errorf("expected the manifest of a removed output directory to be pruned, got %v", err)
+	}
+	if _, err := os.Stat(cachePath(kept)); err != nil {
+		t.Errorf("expected the manifest being written to be kept: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultsFilename = "_defaults.yaml"
+
+// dirDefaults tracks cascading frontmatter defaults while the source tree is
+// walked. A directory's defaults start from its parent's, then its
+// _defaults.yaml, then the cascade: key of its index.md. Deeper directories
+// win over shallower ones, and a page's own frontmatter wins over all of them.
+//
+// An index.md gets its directory's defaults without its own cascade, which
+// only applies to the pages below it.
+type dirDefaults struct {
+	src   string
+	index map[string]*yaml.Node // Directory → defaults for its index.md
+	pages map[string]*yaml.Node // Directory → defaults for its other pages and subdirectories
+}
+
+func newDirDefaults(src string) *dirDefaults {
+	return &dirDefaults{
+		src:   src,
+		index: make(map[string]*yaml.Node),
+		pages: make(map[string]*yaml.Node),
+	}
+}
+
+// enter loads the defaults for relDir ("." for the source root). Parents
+// must be entered before their children, as filepath.WalkDir does.
+//
+// Defaults with invalid fields are still applied, as far as they parse, and
+// reported as a *FrontmatterError naming the file that defines them. A
+// _defaults.yaml that doesn't parse at all is skipped, so relDir and its
+// subdirectories keep what they inherit.
+func (dd *dirDefaults) enter(relDir string) error {
+	var inherited *yaml.Node
+	if relDir != "." {
+		inherited = dd.pages[filepath.Dir(relDir)]
+	}
+
+	defaultsPath := filepath.Join(relDir, defaultsFilename)
+	own, ownErr := readDefaultsFile(dd.src, defaultsPath)
+	indexPath := filepath.Join(relDir, "index.md")
+	cascade, cascadeErr := readCascade(dd.src, indexPath)
+
+	index := mergeFrontmatter(inherited, own)
+	dd.index[relDir] = index
+	dd.pages[relDir] = mergeFrontmatter(index, cascade)
+
+	if ownErr != nil {
+		return ownErr
+	}
+	if _, err := decodeFrontmatter(own); err != nil {
+		return withFrontmatterFile(err, defaultsPath)
+	}
+	if cascadeErr != nil {
+		return cascadeErr
+	}
+	if _, err := decodeFrontmatter(cascade); err != nil {
+		return withFrontmatterFile(err, indexPath)
+	}
+	return nil
+}
+
+// forPage returns the defaults for the page at relPath, or nil if there are none.
+func (dd *dirDefaults) forPage(relPath string) *yaml.Node {
+	dir := filepath.Dir(relPath)
+	if filepath.Base(relPath) == "index.md" {
+		return dd.index[dir]
+	}
+	return dd.pages[dir]
+}
+
+// readDefaultsFile parses a _defaults.yaml. A missing or empty file has no defaults.
+func readDefaultsFile(src, relPath string) (*yaml.Node, error) {
+	data, err := os.ReadFile(filepath.Join(src, relPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", relPath, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line, msg := yamlErrorPosition(err)
+		return nil, &FrontmatterError{File: relPath, Line: line, Msg: msg}
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	fields := mappingNode(&doc)
+	if fields == nil {
+		return nil, &FrontmatterError{File: relPath, Line: doc.Content[0].Line, Msg: "expected frontmatter fields (key: value)"}
+	}
+	return fields, nil
+}
+
+// readCascade returns the cascade: mapping from an index.md's frontmatter,
+// or nil if the file doesn't exist or has none. Errors in the rest of the
+// frontmatter are left for the page itself to report.
+func readCascade(src, relPath string) (*yaml.Node, error) {
+	content, err := os.ReadFile(filepath.Join(src, relPath))
+	if err != nil {
+		return nil, nil
+	}
+	fields, _, err := splitFrontmatter(content)
+	if err != nil {
+		return nil, nil
+	}
+	cascade, err := cascadeNode(fields)
+	return cascade, withFrontmatterFile(err, relPath)
+}
+
+// cascadeNode returns the value of the cascade key in parsed frontmatter,
+// or nil if there is none.
+func cascadeNode(fields *yaml.Node) (*yaml.Node, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(fields.Content); i += 2 {
+		if fields.Content[i].Value != "cascade" {
+			continue
+		}
+		cascade := fields.Content[i+1]
+		if cascade.Kind != yaml.MappingNode {
+			return nil, &FrontmatterError{Line: cascade.Line, Msg: "cascade must be frontmatter fields (key: value)"}
+		}
+		return cascade, nil
+	}
+	return nil, nil
+}
+
+// withFrontmatterFile sets the file on a *FrontmatterError.
+func withFrontmatterFile(err error, relPath string) error {
+	var fmErr *FrontmatterError
+	if errors.As(err, &fmErr) {
+		fmErr.File = relPath
+	}
+	return err
+}
+
+// sourceDigest digests a page's source together with the defaults it was
+// parsed with, so that editing a _defaults.yaml re-renders the pages below it.
+func sourceDigest(content []byte, defaults *yaml.Node) string {
+	if defaults == nil {
+		return digest(content)
+	}
+	data, _ := yaml.Marshal(defaults)
+	return digest(content, data)
+}
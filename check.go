@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CheckIssue is a problem found in a built site, attributed to the source
+// page that caused it.
+type CheckIssue struct {
+	File   string // Source path relative to src, e.g. "01-guide/02-config.md"
+	Line   int    // 1-based line in the source file, 0 if it couldn't be located
+	Kind   string // e.g. "broken link", "missing anchor", "unresolved wikilink"
+	Target string // The offending link target as written or rendered
+}
+
+func (i CheckIssue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, i.Kind, i.Target)
+}
+
+// Check builds src into a temporary directory and validates internal links,
+// heading anchors, wikilinks, and references to _static files.
+func Check(src string, cfg Config) ([]CheckIssue, error) {
+	dst, err := os.MkdirTemp("", "moat-check-")
+	if err != nil {
+		return nil, fmt.Errorf("creating build directory: %w", err)
+	}
+	defer os.RemoveAll(dst)
+
+	disabled := false
+	cfg.Cache = &disabled
+	site, err := buildSite(src, dst, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return checkSite(site), nil
+}
+
+func printIssues(issues []CheckIssue) {
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "%s\n", issue)
+	}
+	fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(issues))
+}
+
+// Link targets in rendered HTML: href="..." / src='...'
+var reLinkAttr = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// Element IDs in rendered HTML, for anchor validation.
+var reIDAttr = regexp.MustCompile(`(?i)\sid\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+// checkSite validates the content of every built page. Links are read from
+// each page's rendered content (not the layout), so every problem can be
+// traced to a source file; anchors are looked up in the full output file.
+func checkSite(site *siteBuild) []CheckIssue {
+	c := &checker{
+		site:        site,
+		ids:         make(map[string]map[string]bool),
+		draftURLs:   make(map[string]bool),
+		draftPaths:  make(map[string]bool),
+		draftTitles: make(map[string]bool),
+	}
+	for _, d := range site.Drafts {
+		c.draftURLs[pageURL(d)] = true
+		c.draftPaths[filepath.ToSlash(d.RelPath)] = true
+		c.draftTitles[strings.ToLower(pageTitle(d))] = true
+	}
+
+	var issues []CheckIssue
+	for _, page := range site.Pages {
+		raw, _ := os.ReadFile(filepath.Join(site.Src, page.RelPath))
+		report := func(kind, target string, needles ...string) {
+			issues = append(issues, CheckIssue{
+				File:   page.RelPath,
+				Line:   sourceLine(raw, append([]string{target}, needles...)...),
+				Kind:   kind,
+				Target: target,
+			})
+		}
+
+		for _, target := range page.Unresolved {
+			kind := "unresolved wikilink"
+			if c.draftTitles[strings.ToLower(target)] {
+				kind = "wikilink to draft"
+			}
+			report(kind, "[["+target+"]]")
+		}
+
+		pageURLPath := pageURL(page)
+		seen := make(map[string]bool)
+		for _, m := range reLinkAttr.FindAllSubmatch(page.HTML, -1) {
+			target := html.UnescapeString(string(m[1]) + string(m[2]))
+			if seen[target] {
+				continue
+			}
+			seen[target] = true
+			if kind := c.checkLink(filepath.ToSlash(page.RelPath), pageURLPath, target); kind != "" {
+				// Rewritten .md links only match the source by their fragment
+				needles := []string{strings.TrimPrefix(target, site.BasePath)}
+				if i := strings.Index(target, "#"); i > 0 {
+					needles = append(needles, target[i:])
+				}
+				report(kind, target, needles...)
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+type checker struct {
+	site        *siteBuild
+	ids         map[string]map[string]bool // output file → element IDs
+	draftURLs   map[string]bool            // URL paths of skipped drafts (no base path)
+	draftPaths  map[string]bool            // slash-separated source paths of drafts
+	draftTitles map[string]bool            // lowercase titles of drafts
+}
+
+// checkLink returns the kind of problem with target as linked from the page
+// with source path fromPath (slash-separated) at fromURL (no base path), or
+// "" if the target is fine or external.
+func (c *checker) checkLink(fromPath, fromURL, target string) string {
+	if target == "" || strings.HasPrefix(target, "//") || reURLScheme.MatchString(target) {
+		return ""
+	}
+
+	p, fragment := target, ""
+	if i := strings.Index(p, "#"); i >= 0 {
+		p, fragment = p[:i], p[i+1:]
+	}
+	if i := strings.Index(p, "?"); i >= 0 {
+		p = p[:i]
+	}
+	// Compare paths and ids as written in the source, not percent-encoded
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+
+	// Fragment-only links point into the current page
+	if p == "" {
+		if fragment != "" && !c.hasID(outputPathFromURL(c.site.Dst, fromURL), fragment) {
+			return "missing anchor"
+		}
+		return ""
+	}
+
+	// Leftover .md links are ones the renderer couldn't resolve; resolve
+	// them the same way to tell drafts from missing files
+	if strings.HasSuffix(p, ".md") {
+		rel := path.Join(path.Dir(fromPath), p)
+		if strings.HasPrefix(p, "/") {
+			rel = strings.TrimPrefix(p, "/")
+		}
+		if c.draftPaths[path.Clean(rel)] {
+			return "link to draft"
+		}
+		return "broken link"
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(fromURL, p)
+		if strings.HasSuffix(target, "/") {
+			p += "/"
+		}
+	} else if c.site.BasePath != "" {
+		if p != c.site.BasePath && !strings.HasPrefix(p, c.site.BasePath+"/") {
+			return "broken link" // Outside the site's base path
+		}
+		p = strings.TrimPrefix(p, c.site.BasePath)
+	}
+	if p == "" {
+		p = "/"
+	}
+
+	dirURL := strings.TrimSuffix(p, "/") + "/"
+	if c.draftURLs[dirURL] {
+		return "link to draft"
+	}
+
+	file := filepath.Join(c.site.Dst, filepath.FromSlash(p))
+	if strings.HasSuffix(p, "/") {
+		file = filepath.Join(file, "index.html")
+	} else if info, err := os.Stat(file); err == nil && info.IsDir() {
+		file = filepath.Join(file, "index.html")
+	}
+	if _, err := os.Stat(file); err != nil {
+		if strings.HasPrefix(p, "/_static/") {
+			return "missing static file"
+		}
+		return "broken link"
+	}
+
+	if fragment != "" && strings.HasSuffix(file, ".html") && !c.hasID(file, fragment) {
+		return "missing anchor"
+	}
+	return ""
+}
+
+// hasID reports whether the HTML file declares an element with the given id.
+func (c *checker) hasID(file, id string) bool {
+	ids, ok := c.ids[file]
+	if !ok {
+		ids = make(map[string]bool)
+		data, _ := os.ReadFile(file)
+		for _, m := range reIDAttr.FindAllSubmatch(data, -1) {
+			ids[html.UnescapeString(string(m[1])+string(m[2]))] = true
+		}
+		c.ids[file] = ids
+	}
+	return ids[id]
+}
+
+// sourceLine returns the 1-based line of the first needle found in source,
+// or 0 if none occur. Rendered links may differ from what the author wrote,
+// so callers pass several spellings.
+func sourceLine(source []byte, needles ...string) int {
+	for _, needle := range needles {
+		if needle == "" {
+			continue
+		}
+		if i := bytes.Index(source, []byte(needle)); i >= 0 {
+			return bytes.Count(source[:i], []byte("\n")) + 1
+		}
+	}
+	return 0
+}
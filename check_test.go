@@ -0,0 +1,105 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckReportsBrokenLinks(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	writeTestFile(t, filepath.Join(src, "01-guide", "01-intro.md"), `---
+title: Intro
+---
+
+## Getting started
+
+See [setup](02-setup.md) and [[Setup]].
+`)
+	writeTestFile(t, filepath.Join(src, "01-guide", "02-setup.md"), `---
+title: Setup
+---
+
+Back to [intro](01-intro.md#getting-started) or [top](#setup).
+
+A [missing page](/guide/nowhere/) and a [bad anchor](01-intro.md#nope).
+
+Link to [[Roadmap]] and [[Nothing]].
+
+![logo](/_static/missing.png)
+`)
+	writeTestFile(t, filepath.Join(src, "01-guide", "03-roadmap.md"), "---\ntitle: Roadmap\ndraft: true\n---\n")
+
+	issues, err := Check(src, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CheckIssue{
+		{File: "01-guide/02-setup.md", Line: 5, Kind: "missing anchor", Target: "#setup"},
+		{File: "01-guide/02-setup.md", Line: 7, Kind: "broken link", Target: "/guide/nowhere/"},
+		{File: "01-guide/02-setup.md", Line: 7, Kind: "missing anchor", Target: "/guide/intro/#nope"},
+		{File: "01-guide/02-setup.md", Line: 9, Kind: "wikilink to draft", Target: "[[Roadmap]]"},
+		{File: "01-guide/02-setup.md", Line: 9, Kind: "unresolved wikilink", Target: "[[Nothing]]"},
+		{File: "01-guide/02-setup.md", Line: 11, Kind: "missing static file", Target: "/_static/missing.png"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%v", len(issues), len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issues[%d] = %v, want %v", i, issues[i], want[i])
+		}
+	}
+}
+
+func TestBuildStrictFailsOnBrokenLinks(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n\n[gone](gone.md)\n")
+
+	if err := Build(src, t.TempDir(), Config{}); err != nil {
+		t.Fatalf("non-strict build should succeed: %v", err)
+	}
+	if err := Build(src, t.TempDir(), Config{Strict: true}); err == nil {
+		t.Fatal("strict build should fail on a broken link")
+	}
+}
+
+func TestCheckIssueString(t *testing.T) {
+	issue := CheckIssue{File: "guide/intro.md", Line: 12, Kind: "broken link", Target: "/nope/"}
+	if got, want := issue.String(), "guide/intro.md:12: broken link: /nope/"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckDecodesLinksAndMatchesDraftsExactly(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), `# Home
+
+## Café
+
+Jump to [the café](#café) or visit [Crème](/crème/#café).
+
+Read the [notes](notes.md) and the [other notes](other-notes.md).
+`)
+	writeTestFile(t, filepath.Join(src, "crème.md"), "## Café\n")
+	writeTestFile(t, filepath.Join(src, "notes.md"), "---\ndraft: true\n---\n")
+
+	issues, err := Check(src, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CheckIssue{
+		{File: "index.md", Line: 7, Kind: "link to draft", Target: "notes.md"},
+		{File: "index.md", Line: 7, Kind: "broken link", Target: "other-notes.md"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%v", len(issues), len(want), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issues[%d] = %v, want %v", i, issues[i], want[i])
+		}
+	}
+}
@@ -3,25 +3,40 @@ package main
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
 
 // Config holds site-level configuration from config.toml.
 type Config struct {
-	SiteName            string          `toml:"site_name"`
-	BasePath            string          `toml:"base_path"`
-	Logo                string          `toml:"logo"`
-	Favicon             string          `toml:"favicon"`
-	FooterText          string          `toml:"footer_text"`
-	DisableMoatCitation bool            `toml:"disable_moat_citation"`
-	Highlight           HighlightConfig `toml:"highlight"`
-	Links               []LinkConfig    `toml:"links"`
-	TopNav              []LinkConfig    `toml:"topnav"`
-	TopNavMore          []LinkConfig    `toml:"topnav_more"`
-	Search              SearchConfig    `toml:"search"`
-	Feed                FeedConfig      `toml:"feed"`
-	Extra               map[string]any  `toml:"extra"`
+	SiteName            string            `toml:"site_name"`
+	BasePath            string            `toml:"base_path"`
+	SiteURL             string            `toml:"site_url"` // Absolute URL of the site root, including base_path
+	Logo                string            `toml:"logo"`
+	Favicon             string            `toml:"favicon"`
+	FooterText          string            `toml:"footer_text"`
+	DisableMoatCitation bool              `toml:"disable_moat_citation"`
+	Highlight           HighlightConfig   `toml:"highlight"`
+	HeadingAnchors      bool              `toml:"heading_anchors"`
+	Links               []LinkConfig      `toml:"links"`
+	TopNav              []LinkConfig      `toml:"topnav"`
+	TopNavMore          []LinkConfig      `toml:"topnav_more"`
+	Nav                 []NavEntry        `toml:"nav"` // Explicit sidebar nav; see also _nav.yaml
+	Search              SearchConfig      `toml:"search"`
+	Feed                FeedConfig        `toml:"feed"`
+	Cache               *bool             `toml:"cache"`
+	Jobs                int               `toml:"jobs"`
+	Strict              bool              `toml:"strict"`
+	Lenient             bool              `toml:"lenient"`    // Warn about invalid frontmatter instead of failing
+	Drafts              bool              `toml:"drafts"`     // Build draft: true pages
+	Future              bool              `toml:"future"`     // Build pages whose publish_date is still to come
+	Expired             bool              `toml:"expired"`    // Build pages past their expiry_date
+	Timezone            string            `toml:"timezone"`   // IANA zone for dates without an offset, e.g. "Europe/Berlin"; defaults to UTC
+	Paginate            int               `toml:"paginate"`   // Pages per term page; 0 lists them all on one
+	Taxonomies          map[string]string `toml:"taxonomies"` // Frontmatter field → URL path of its term pages, e.g. tags = "tags"
+	Extra               map[string]any    `toml:"extra"`
 }
 
 // LinkConfig is a sidebar link above the nav.
@@ -51,6 +66,38 @@ func (c Config) SearchEnabled() bool {
 	return *c.Search.Enabled
 }
 
+// CacheEnabled returns the effective incremental build setting.
+// The build cache defaults to enabled when omitted from config.toml.
+func (c Config) CacheEnabled() bool {
+	if c.Cache == nil {
+		return true
+	}
+	return *c.Cache
+}
+
+// EffectiveJobs returns how many pages render concurrently.
+// Defaults to GOMAXPROCS when jobs is unset or not positive.
+func (c Config) EffectiveJobs() int {
+	if c.Jobs <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return c.Jobs
+}
+
+// Location returns the time zone that dates without an offset are read in:
+// timezone if set, otherwise UTC, so a build doesn't depend on the zone of
+// the machine running it.
+func (c Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q in config (expected a name like \"Europe/Berlin\")", c.Timezone)
+	}
+	return loc, nil
+}
+
 // LoadConfig reads a TOML config file. Returns zero Config if path is empty or file doesn't exist.
 func LoadConfig(path string) (Config, error) {
 	var cfg Config
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const dataDirname = "_data"
+
+// DataError reports a data file that could not be parsed.
+type DataError struct {
+	File string // Path relative to the source directory, e.g. "_data/team.yaml"
+	Line int    // 1-based; 0 when the parser gives no position
+	Msg  string
+}
+
+func (e *DataError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// loadData reads every YAML, TOML, and JSON file under _data/ into one map
+// keyed by file name without extension. Subdirectories become nested maps:
+// _data/releases/v1.yaml is .Data.releases.v1. Also returns a digest of the
+// files for the build cache. A missing _data/ gives an empty map.
+func loadData(src string) (map[string]any, string, error) {
+	data := make(map[string]any)
+	dir := filepath.Join(src, dataDirname)
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".toml", ".json":
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", dataDirname, err)
+	}
+	sort.Strings(files)
+
+	var parts [][]byte
+	defined := make(map[string]string) // Key path → file that set it
+	for _, path := range files {
+		rel, _ := filepath.Rel(src, path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading %s: %w", rel, err)
+		}
+		value, err := parseDataFile(rel, content)
+		if err != nil {
+			return nil, "", err
+		}
+
+		inDir, _ := filepath.Rel(dir, path)
+		keys := strings.Split(filepath.ToSlash(strings.TrimSuffix(inDir, filepath.Ext(inDir))), "/")
+		if err := setDataValue(data, keys, value, rel, defined); err != nil {
+			return nil, "", err
+		}
+		parts = append(parts, []byte(rel), content)
+		fmt.Printf("  Data: %s\n", rel)
+	}
+
+	return data, digest(parts...), nil
+}
+
+// setDataValue stores value at keys in data, creating nested maps for
+// directories. Two files can't claim the same key, and a file can't share a
+// name with a directory.
+func setDataValue(data map[string]any, keys []string, value any, file string, defined map[string]string) error {
+	m := data
+	for i, key := range keys[:len(keys)-1] {
+		path := strings.Join(keys[:i+1], ".")
+		if other, ok := defined[path]; ok {
+			return fmt.Errorf("%s and %s both define data %q", other, file, path)
+		}
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[key] = next
+		}
+		m = next
+	}
+
+	path := strings.Join(keys, ".")
+	if other, ok := defined[path]; ok {
+		return fmt.Errorf("%s and %s both define data %q", other, file, path)
+	}
+	if _, ok := m[keys[len(keys)-1]]; ok {
+		return fmt.Errorf("%s: data %q is also a directory", file, path)
+	}
+	m[keys[len(keys)-1]] = value
+	defined[path] = file
+	return nil
+}
+
+// parseDataFile decodes a data file by extension, reporting errors as a
+// *DataError with the line where parsing failed.
+func parseDataFile(rel string, content []byte) (any, error) {
+	var value any
+	switch strings.ToLower(filepath.Ext(rel)) {
+	case ".toml":
+		var m map[string]any
+		if err := toml.Unmarshal(content, &m); err != nil {
+			var perr toml.ParseError
+			if errors.As(err, &perr) {
+				return nil, &DataError{File: rel, Line: perr.Position.Line, Msg: perr.Message}
+			}
+			return nil, &DataError{File: rel, Msg: err.Error()}
+		}
+		value = m
+
+	case ".json":
+		if err := json.Unmarshal(content, &value); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case errors.As(err, &syntaxErr):
+				line, _ := sourcePosition(content, int(syntaxErr.Offset))
+				return nil, &DataError{File: rel, Line: line, Msg: syntaxErr.Error()}
+			case errors.As(err, &typeErr):
+				line, _ := sourcePosition(content, int(typeErr.Offset))
+				return nil, &DataError{File: rel, Line: line, Msg: typeErr.Error()}
+			}
+			return nil, &DataError{File: rel, Msg: err.Error()}
+		}
+
+	default: // .yaml, .yml
+		if err := yaml.Unmarshal(content, &value); err != nil {
+			line, msg := yamlErrorPosition(err)
+			return nil, &DataError{File: rel, Line: line, Msg: msg}
+		}
+	}
+	return value, nil
+}
+
+// reYAMLLine pulls the line number out of a yaml.v3 error message.
+var reYAMLLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// yamlErrorPosition splits a yaml.v3 error into its line (0 if unknown) and
+// message. Of several type errors, only the first is returned.
+func yamlErrorPosition(err error) (int, string) {
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	if m := reYAMLLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, m[2]
+	}
+	return 0, strings.TrimPrefix(msg, "yaml: ")
+}
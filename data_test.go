@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadData(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_data", "team.yaml"), "- name: Ada\n- name: Lin\n")
+	writeTestFile(t, filepath.Join(src, "_data", "site.toml"), "version = \"1.2\"\n")
+	writeTestFile(t, filepath.Join(src, "_data", "releases", "v1.json"), `{"date": "2026-01-05", "lts": true}`)
+	writeTestFile(t, filepath.Join(src, "_data", "notes.txt"), "ignored")
+
+	data, _, err := loadData(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if team, _ := data["team"].([]any); len(team) != 2 {
+		t.Errorf("team = %v, want two entries", data["team"])
+	}
+	if site, _ := data["site"].(map[string]any); site["version"] != "1.2" {
+		t.Errorf("site = %v, want version 1.2", data["site"])
+	}
+	releases, _ := data["releases"].(map[string]any)
+	if v1, _ := releases["v1"].(map[string]any); v1["lts"] != true {
+		t.Errorf("releases = %v, want nested v1 map", data["releases"])
+	}
+	if _, ok := data["notes"]; ok {
+		t.Error("non-data files should be skipped")
+	}
+}
+
+func TestLoadDataMissingDir(t *testing.T) {
+	data, _, err := loadData(t.TempDir())
+	if err != nil || len(data) != 0 {
+		t.Errorf("loadData without _data = %v, %v; want empty map", data, err)
+	}
+}
+
+func TestLoadDataErrors(t *testing.T) {
+	tests := []struct {
+		file, content, want string
+		line                int
+	}{
+		{"bad.yaml", "a: 1\nb: c: d\n", "_data/bad.yaml:2:", 2},
+		{"dup.yaml", "a: 1\nb: 2\na: 3\n", "_data/dup.yaml:3:", 3},
+		{"bad.toml", "a = 1\nb = \n", "_data/bad.toml:2:", 2},
+		{"bad.json", "{\n  \"a\": 1,\n  \"b\": }\n", "_data/bad.json:3:", 3},
+	}
+	for _, tt := range tests {
+		src := t.TempDir()
+		writeTestFile(t, filepath.Join(src, "_data", tt.file), tt.content)
+		_, _, err := loadData(src)
+		var dataErr *DataError
+		if !errors.As(err, &dataErr) {
+			t.Errorf("%s: got %v, want a DataError", tt.file, err)
+			continue
+		}
+		if dataErr.Line != tt.line || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: got %q (line %d), want prefix %q on line %d", tt.file, err, dataErr.Line, tt.want, tt.line)
+		}
+	}
+}
+
+func TestLoadDataConflicts(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_data", "team.yaml"), "a: 1\n")
+	writeTestFile(t, filepath.Join(src, "_data", "team.json"), `{"a": 2}`)
+	if _, _, err := loadData(src); err == nil || !strings.Contains(err.Error(), `both define data "team"`) {
+		t.Errorf("got %v, want a conflict error", err)
+	}
+}
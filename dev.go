@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// devEventsPath is the server-sent events endpoint used for live reload.
+const devEventsPath = "/_moat/events"
+
+// devPollInterval is how often the source tree is checked for changes.
+const devPollInterval = 300 * time.Millisecond
+
+// devShutdownTimeout bounds how long Dev waits for requests in flight
+// when it stops.
+const devShutdownTimeout = 5 * time.Second
+
+// Dev watches src, rebuilds into a temporary directory whenever something
+// changes, and serves the result with live reload. Config is reloaded on
+// every rebuild so edits to config.toml take effect without a restart.
+// Ctrl-C or SIGTERM stops the server and removes the build and its manifest.
+func Dev(src, port string, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dst, err := os.MkdirTemp("", "moat-dev-")
+	if err != nil {
+		return fmt.Errorf("creating build directory: %w", err)
+	}
+	defer os.RemoveAll(dst)
+	defer removeCache(dst)
+
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	return runDev(ctx, ln, src, dst, args)
+}
+
+// runDev builds src into dst, then serves it on ln and rebuilds on changes
+// until ctx is done. Open live-reload connections are closed with ctx.
+func runDev(ctx context.Context, ln net.Listener, src, dst string, args []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	configPath, _ := flagValue(args, "--config")
+	srv := newDevServer(dst)
+
+	build := func() {
+		cfg, err := loadBuildConfig(src, args)
+		if err == nil {
+			srv.setBasePath(cfg.BasePath)
+			err = Build(src, dst, cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		srv.finishBuild(err)
+	}
+	build()
+
+	srv.mu.Lock()
+	basePath := srv.basePath
+	srv.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(devPollInterval)
+		defer ticker.Stop()
+		prev, _ := snapshotTree(src, configPath)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cur, err := snapshotTree(src, configPath)
+			if err != nil || cur.equal(prev) {
+				continue
+			}
+			prev = cur
+			fmt.Printf("Change detected, rebuilding…\n")
+			build()
+		}
+	}()
+
+	server := &http.Server{
+		Handler:     srv,
+		BaseContext: func(net.Listener) context.Context { return ctx }, // Ends event streams on shutdown
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	fmt.Printf("Watching %s, serving on http://localhost:%s%s/\n", src, port, basePath)
+
+	var err error
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+		fmt.Printf("Shutting down…\n")
+		shutdownCtx, done := context.WithTimeout(context.Background(), devShutdownTimeout)
+		defer done()
+		err = server.Shutdown(shutdownCtx)
+	}
+	cancel()
+	wg.Wait() // A rebuild in progress finishes before dst is removed
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
+
+// devServer serves a built site, injecting a live-reload client into HTML
+// pages and showing the last build error as an in-page overlay.
+type devServer struct {
+	dir string
+
+	mu       sync.Mutex
+	basePath string
+	buildErr error
+	clients  map[chan devEvent]struct{}
+}
+
+// devEvent is a message pushed to connected browsers.
+type devEvent struct {
+	name string // "reload" or "build-error"
+	data string
+}
+
+func newDevServer(dir string) *devServer {
+	return &devServer{dir: dir, clients: make(map[chan devEvent]struct{})}
+}
+
+func (s *devServer) setBasePath(basePath string) {
+	s.mu.Lock()
+	s.basePath = strings.TrimRight(basePath, "/")
+	s.mu.Unlock()
+}
+
+// finishBuild records the build result and notifies connected browsers:
+// a successful build reloads the page, a failed one shows the overlay.
+func (s *devServer) finishBuild(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildErr = err
+
+	ev := devEvent{name: "reload"}
+	if err != nil {
+		ev = devEvent{name: "build-error", data: err.Error()}
+	}
+	for ch := range s.clients {
+		select {
+		case ch <- ev:
+		default: // Client is behind; it will catch up on the next event
+		}
+	}
+}
+
+func (s *devServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == devEventsPath {
+		s.serveEvents(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	basePath, buildErr := s.basePath, s.buildErr
+	s.mu.Unlock()
+
+	urlPath := r.URL.Path
+	if basePath != "" {
+		if urlPath == "/" {
+			http.Redirect(w, r, basePath+"/", http.StatusFound)
+			return
+		}
+		if urlPath != basePath && !strings.HasPrefix(urlPath, basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		urlPath = strings.TrimPrefix(urlPath, basePath)
+	}
+
+	file := filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+urlPath)))
+	if info, err := os.Stat(file); err == nil && info.IsDir() && !strings.HasSuffix(urlPath, "/") {
+		http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+		return
+	}
+	if strings.HasSuffix(urlPath, "/") {
+		file = filepath.Join(file, "index.html")
+	}
+
+	if !strings.HasSuffix(file, ".html") {
+		http.ServeFile(w, r, file)
+		return
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if buildErr == nil {
+			http.NotFound(w, r)
+			return
+		}
+		// The page may be missing because the build failed; show why.
+		w.WriteHeader(http.StatusInternalServerError)
+		data = []byte("<!DOCTYPE html>\n<html>\n<head><title>Build failed</title></head>\n<body></body>\n</html>\n")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(injectDevClient(data, buildErr))
+}
+
+func (s *devServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan devEvent, 1)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			data, _ := json.Marshal(ev.data)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// devClientScript connects to the events endpoint, reloads on a successful
+// rebuild, and renders build errors in a fixed overlay. %s is replaced with
+// the JSON-encoded current build error (empty string if none).
+const devClientScript = `<script>
+(function() {
+  var overlay = null;
+  function showError(msg) {
+    if (!overlay) {
+      overlay = document.createElement('div');
+      overlay.id = 'moat-dev-error';
+      overlay.setAttribute('role', 'alert');
+      overlay.style.cssText = 'position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:2rem;background:rgba(20,20,20,.92);color:#ffb4b4;font:14px/1.5 ui-monospace,monospace;white-space:pre-wrap';
+      document.body.appendChild(overlay);
+    }
+    overlay.textContent = 'moat: build failed\n\n' + msg;
+  }
+  var initial = %s;
+  if (initial) showError(initial);
+  var es = new EventSource('` + devEventsPath + `');
+  es.addEventListener('reload', function() { location.reload(); });
+  es.addEventListener('build-error', function(e) { showError(JSON.parse(e.data)); });
+})();
+</script>
+`
+
+// injectDevClient inserts the live-reload client before </body>, or appends
+// it when the page has no closing body tag.
+func injectDevClient(page []byte, buildErr error) []byte {
+	msg := ""
+	if buildErr != nil {
+		msg = buildErr.Error()
+	}
+	encoded, _ := json.Marshal(msg)
+	script := []byte(fmt.Sprintf(devClientScript, encoded))
+
+	idx := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if idx < 0 {
+		return append(page, script...)
+	}
+	out := make([]byte, 0, len(page)+len(script))
+	out = append(out, page[:idx]...)
+	out = append(out, script...)
+	return append(out, page[idx:]...)
+}
+
+// fileStamp identifies a version of a watched file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// treeSnapshot maps watched file paths to their current stamps.
+type treeSnapshot map[string]fileStamp
+
+func (a treeSnapshot) equal(b treeSnapshot) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for p, sa := range a {
+		sb, ok := b[p]
+		if !ok || sa.size != sb.size || !sa.modTime.Equal(sb.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshotTree records size and modification time of every file under root
+// (skipping dot-prefixed paths such as .git) plus any extra files given.
+// Polling keeps moat free of platform-specific file watching dependencies.
+func snapshotTree(root string, extra ...string) (treeSnapshot, error) {
+	snap := make(treeSnapshot)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed mid-walk; the next poll sees it
+		}
+		snap[p] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	for _, p := range extra {
+		if p == "" {
+			continue
+		}
+		if info, err := os.Stat(p); err == nil {
+			snap[p] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return snap, err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInjectDevClient(t *testing.T) {
+	page := []byte("<html><body><p>hi</p></body></html>")
+	out := string(injectDevClient(page, nil))
+
+	script := strings.Index(out, devEventsPath)
+	body := strings.Index(out, "</body>")
+	if script < 0 || body < 0 || script > body {
+		t.Fatalf("expected live-reload script before </body>, got: %s", out)
+	}
+	if !strings.Contains(out, `var initial = "";`) {
+		t.Errorf("expected empty initial error, got: %s", out)
+	}
+
+	// Pages without </body> get the script appended
+	out = string(injectDevClient([]byte("<p>fragment</p>"), errors.New(`bad <shortcode>`)))
+	if !strings.HasPrefix(out, "<p>fragment</p><script>") {
+		t.Errorf("expected script appended, got: %s", out)
+	}
+	if strings.Contains(out, "bad <shortcode>") {
+		t.Errorf("build error should be JSON-escaped inside the script, got: %s", out)
+	}
+}
+
+func TestDevServerBasePathAndErrorOverlay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "guide"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "guide", "index.html"), []byte("<body>guide</body>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newDevServer(dir)
+	srv.setBasePath("/moat/")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/moat/guide/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), devEventsPath) {
+		t.Fatalf("expected page with live-reload client, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/moat/" {
+		t.Errorf("expected redirect to base path, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// A missing page after a failed build shows the error instead of a 404
+	srv.finishBuild(errors.New("missing default layout _layout.html"))
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/moat/gone/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for missing page after failed build, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "missing default layout") {
+		t.Errorf("expected build error in overlay, got: %s", rec.Body)
+	}
+}
+
+func TestSnapshotTreeDetectsChanges(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.md")
+	if err := os.WriteFile(page, []byte("# Hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := snapshotTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes under dot-directories are ignored
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	same, _ := snapshotTree(dir)
+	if !before.equal(same) {
+		t.Error("expected dot-directory changes to be ignored")
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(page, later, later); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := snapshotTree(dir)
+	if before.equal(after) {
+		t.Error("expected modified file to change the snapshot")
+	}
+}
+
+func TestRunDevStopsWithContext(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "index.md"), "# Home\n")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runDev(ctx, ln, src, t.TempDir(), nil) }()
+
+	// An open live-reload stream must not hold up shutdown
+	resp, err := http.Get("http://" + ln.Addr().String() + devEventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runDev = %v, want nil after cancel", err)
+		}
+	case <-time.After(devShutdownTimeout / 2):
+		t.Fatal("runDev did not stop after its context was done")
+	}
+}
+
+func TestDevBuildErrorEvent(t *testing.T) {
+	srv := newDevServer(t.TempDir())
+	ch := make(chan devEvent, 1)
+	srv.clients[ch] = struct{}{}
+
+	// EventSource fires its own "error" on dropped connections, so build
+	// failures need a name of their own
+	srv.finishBuild(errors.New("bad layout"))
+	if ev := <-ch; ev.name != "build-error" || ev.data != "bad layout" {
+		t.Errorf("event = %+v, want build-error", ev)
+	}
+	if !strings.Contains(devClientScript, `addEventListener('build-error'`) || strings.Contains(devClientScript, `addEventListener('error'`) {
+		t.Error("client should listen for build-error, not error")
+	}
+}
@@ -52,9 +52,13 @@ type rssItem struct {
 }
 
 // buildFeed creates an RSS 2.0 feed from rendered pages.
-// Only pages with a valid YYYY-MM-DD date are included, sorted newest first.
+// Only pages with a valid date are included, sorted newest first. Dates
+// keep the site time zone, so pubDate carries its offset.
 func buildFeed(pages []Page, cfg Config) rssFeed {
 	siteLink := cfg.Feed.Link
+	if siteLink == "" {
+		siteLink = cfg.SiteURL
+	}
 	if siteLink == "" {
 		siteLink = "/"
 	}
@@ -68,32 +72,27 @@ func buildFeed(pages []Page, cfg Config) rssFeed {
 		feedTitle = "Site"
 	}
 
-	type feedPage struct {
-		page Page
-		date time.Time
-	}
-	var datedPages []feedPage
+	var datedPages []Page
 	for _, page := range pages {
-		if page.Frontmatter.Date == "" {
-			continue
+		if !page.DateTime.IsZero() {
+			datedPages = append(datedPages, page)
 		}
-		t, ok := ParseDate(page.Frontmatter.Date)
-		if !ok {
-			continue
-		}
-		datedPages = append(datedPages, feedPage{page: page, date: t})
 	}
 
 	sort.Slice(datedPages, func(i, j int) bool {
-		if !datedPages[i].date.Equal(datedPages[j].date) {
-			return datedPages[i].date.After(datedPages[j].date)
+		if !datedPages[i].DateTime.Equal(datedPages[j].DateTime) {
+			return datedPages[i].DateTime.After(datedPages[j].DateTime)
 		}
-		return datedPages[i].page.RelPath < datedPages[j].page.RelPath
+		return datedPages[i].RelPath < datedPages[j].RelPath
 	})
 
+	now := time.Now()
+	if loc, err := cfg.Location(); err == nil {
+		now = now.In(loc)
+	}
+
 	items := make([]rssItem, 0, len(datedPages))
-	for _, fp := range datedPages {
-		page := fp.page
+	for _, page := range datedPages {
 		// feed.link is the full site root (e.g. https://example.com/moat)
 		// so we only append the page URL path, not basePath again.
 		fullURL := siteLink + pageURL(page)
@@ -117,7 +116,7 @@ func buildFeed(pages []Page, cfg Config) rssFeed {
 			Title:       title,
 			Link:        fullURL,
 			Description: desc,
-			PubDate:     fp.date.Format(time.RFC1123Z),
+			PubDate:     page.DateTime.Format(time.RFC1123Z),
 			GUID:        fullURL,
 		})
 	}
@@ -136,7 +135,7 @@ func buildFeed(pages []Page, cfg Config) rssFeed {
 			Title:       feedTitle,
 			Link:        siteLink,
 			Description: channelDesc,
-			BuildDate:   time.Now().Format(time.RFC1123Z),
+			BuildDate:   now.Format(time.RFC1123Z),
 			Items:       items,
 		},
 	}
@@ -5,7 +5,7 @@ import (
 )
 
 func TestBuildFeedIncludesOnlyDatedPagesNewestFirst(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{
 			RelPath:     "posts/older.md",
 			Frontmatter: Frontmatter{Title: "Older Post", Date: "2026-01-01", Description: "Older"},
@@ -21,7 +21,7 @@ func TestBuildFeedIncludesOnlyDatedPagesNewestFirst(t *testing.T) {
 			Frontmatter: Frontmatter{Title: "Newer Post", Date: "2026-03-18", Description: "Newer"},
 			HTML:        []byte("<p>Newer content</p>"),
 		},
-	}
+	})
 
 	cfg := Config{
 		SiteName: "Test Site",
@@ -60,10 +60,10 @@ func TestBuildFeedIncludesOnlyDatedPagesNewestFirst(t *testing.T) {
 }
 
 func TestBuildFeedAcceptsTimestamps(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "posts/a.md", Frontmatter: Frontmatter{Title: "Morning", Date: "2026-03-18 09:00"}, HTML: []byte("<p>x</p>")},
 		{RelPath: "posts/b.md", Frontmatter: Frontmatter{Title: "Evening", Date: "2026-03-18 21:00"}, HTML: []byte("<p>x</p>")},
-	}
+	})
 
 	feed := buildFeed(pages, Config{SiteName: "Test", Feed: FeedConfig{Link: "https://example.com"}})
 	if len(feed.Channel.Items) != 2 {
@@ -76,10 +76,10 @@ func TestBuildFeedAcceptsTimestamps(t *testing.T) {
 }
 
 func TestBuildFeedSkipsInvalidDates(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "posts/bad.md", Frontmatter: Frontmatter{Title: "Bad", Date: "18-03-2026"}, HTML: []byte("<p>x</p>")},
 		{RelPath: "posts/good.md", Frontmatter: Frontmatter{Title: "Good", Date: "2026-03-18"}, HTML: []byte("<p>x</p>")},
-	}
+	})
 
 	feed := buildFeed(pages, Config{SiteName: "My Site", Feed: FeedConfig{Link: "https://example.com"}})
 	if len(feed.Channel.Items) != 1 {
@@ -92,9 +92,9 @@ func TestBuildFeedSkipsInvalidDates(t *testing.T) {
 
 func TestBuildFeedLinkIncludesBasePath(t *testing.T) {
 	// feed.link should be the full site root — basePath is NOT appended again
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "posts/hello.md", Frontmatter: Frontmatter{Title: "Hello", Date: "2026-03-18"}, HTML: []byte("<p>hi</p>")},
-	}
+	})
 
 	cfg := Config{
 		SiteName: "Test",
@@ -2,16 +2,24 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
 // dateFormats are the accepted date formats in frontmatter, tried in order.
-// All are ISO-style so string comparison works for sorting.
+// Dates with an offset keep it; the rest are read in the site time zone.
 var dateFormats = []string{
+	"2006-01-02T15:04:05Z07:00", // RFC 3339; fractional seconds are accepted too
+	"2006-01-02 15:04:05Z07:00",
 	"2006-01-02T15:04:05",
 	"2006-01-02 15:04:05",
 	"2006-01-02T15:04",
@@ -19,18 +27,55 @@ var dateFormats = []string{
 	"2006-01-02",
 }
 
-// ParseDate tries to parse a frontmatter date string.
-// Returns the parsed time and true on success, or zero time and false.
+// ParseDate tries to parse a frontmatter date string, reading dates without
+// an offset as UTC. Returns the parsed time and true on success, or zero
+// time and false.
 func ParseDate(s string) (time.Time, bool) {
+	return parseDateIn(s, time.UTC)
+}
+
+// parseDateIn parses a frontmatter date, reading dates without an offset in
+// loc. Dates with an offset are converted to loc, so every parsed date
+// compares and formats in the same zone.
+func parseDateIn(s string, loc *time.Location) (time.Time, bool) {
 	s = strings.TrimSpace(s)
-	for _, fmt := range dateFormats {
-		if t, err := time.Parse(fmt, s); err == nil {
-			return t, true
+	for _, layout := range dateFormats {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.In(loc), true
 		}
 	}
 	return time.Time{}, false
 }
 
+// Page statuses for pages left out of a normal build, named after the flag
+// that builds them anyway.
+const (
+	statusDraft   = "draft"   // draft: true (--drafts)
+	statusFuture  = "future"  // publish_date, or date, is still to come (--future)
+	statusExpired = "expired" // expiry_date has passed (--expired)
+)
+
+// pageStatuses returns every reason a page is left out of a normal build at
+// now, most important first; none for a published page. Dates without an
+// offset are read in loc.
+func pageStatuses(fm Frontmatter, now time.Time, loc *time.Location) []string {
+	var statuses []string
+	if fm.Draft {
+		statuses = append(statuses, statusDraft)
+	}
+	publish := fm.PublishDate
+	if publish == "" {
+		publish = fm.Date
+	}
+	if t, ok := parseDateIn(publish, loc); ok && t.After(now) {
+		statuses = append(statuses, statusFuture)
+	}
+	if t, ok := parseDateIn(fm.ExpiryDate, loc); ok && !t.After(now) {
+		statuses = append(statuses, statusExpired)
+	}
+	return statuses
+}
+
 // Frontmatter holds YAML metadata from the top of a markdown file.
 type Frontmatter struct {
 	Title       string         `yaml:"title"`
@@ -39,55 +84,369 @@ type Frontmatter struct {
 	Layout      string         `yaml:"layout"`
 	Date        string         `yaml:"date"`
 	Draft       bool           `yaml:"draft"`
-	Extra       map[string]any `yaml:"-"` // All other fields
+	PublishDate string         `yaml:"publish_date"` // Left out of the build before this date; defaults to date
+	ExpiryDate  string         `yaml:"expiry_date"`  // Left out of the build from this date on
+	Lastmod     string         `yaml:"lastmod"`      // Last modified; defaults to date
+	Weight      int            `yaml:"weight"`       // Nav position; lower first, 0 = unweighted
+	NavTitle    string         `yaml:"nav_title"`    // Shorter label for the sidebar
+	NavHidden   bool           `yaml:"nav_hidden"`   // Build the page but leave it out of the nav
+	NavOrder    string         `yaml:"nav_order"`    // On a section index.md: "date", "title", or "weight"
+	TOC         *bool          `yaml:"toc"`          // Table of contents; defaults to on
+	TOCLevels   string         `yaml:"toc_levels"`   // Heading levels in the TOC, e.g. "2-3"
+	Paginate    int            `yaml:"paginate"`     // On a section index.md: pages per page of its list
+	Extra       map[string]any `yaml:"-"`            // All other fields
+}
+
+// FrontmatterError reports frontmatter that could not be parsed.
+type FrontmatterError struct {
+	File string // Source path, set by the build; empty for bare markdown
+	Line int    // 1-based line in the file; 0 when the parser gives no position
+	Msg  string
+}
+
+func (e *FrontmatterError) Error() string {
+	switch {
+	case e.File == "":
+		return fmt.Sprintf("frontmatter line %d: %s", e.Line, e.Msg)
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
 }
 
 // ParseFrontmatter splits a markdown file into frontmatter and body.
-// If no frontmatter delimiter (---) is found, returns empty Frontmatter and the full content.
-func ParseFrontmatter(content []byte) (Frontmatter, []byte) {
-	var fm Frontmatter
+// Frontmatter can be YAML (---), TOML (+++), or a JSON object.
+// If none is found, returns empty Frontmatter and the full content.
+// Invalid syntax, or a field of the wrong type, returns a *FrontmatterError
+// along with whatever could be parsed.
+func ParseFrontmatter(content []byte) (Frontmatter, []byte, error) {
+	return parseFrontmatter(content, nil)
+}
+
+// parseFrontmatter is ParseFrontmatter with cascaded defaults: fields the
+// page doesn't set are taken from defaults, a YAML mapping (see dirDefaults).
+func parseFrontmatter(content []byte, defaults *yaml.Node) (Frontmatter, []byte, error) {
+	fields, body, err := splitFrontmatter(content)
+	if err != nil {
+		return Frontmatter{}, body, err
+	}
+	fm, err := decodeFrontmatter(mergeFrontmatter(defaults, fields))
+	if err != nil && defaults != nil {
+		// Report only the page's own mistakes; defaults are checked where
+		// they're defined
+		_, err = decodeFrontmatter(fields)
+	}
+	return fm, body, err
+}
+
+// splitFrontmatter separates the frontmatter from the body and parses it into
+// a mapping node. Frontmatter is YAML between --- lines, TOML between +++
+// lines, or a JSON object at the very start of the file; all three become a
+// YAML mapping so that they share defaults, decoding, and error reporting.
+// Without frontmatter the node is nil and body is the full content.
+func splitFrontmatter(content []byte) (*yaml.Node, []byte, error) {
+	if reJSONFrontmatter.Match(content) {
+		return splitJSONFrontmatter(content)
+	}
 
-	s := string(content)
-	if !strings.HasPrefix(s, "---\n") && !strings.HasPrefix(s, "---\r\n") {
-		return fm, content
+	block, body, delim := cutFrontmatter(string(content))
+	if delim == "" {
+		return nil, content, nil
 	}
 
-	// Find closing ---
-	rest := s[4:] // skip opening "---\n"
-	idx := strings.Index(rest, "\n---")
-	if idx < 0 {
-		return fm, content
+	// The leading newline stands in for the opening delimiter, so line
+	// numbers in nodes and errors match the file
+	if delim == "+++" {
+		fields, err := parseTOMLFrontmatter("\n" + block)
+		return fields, []byte(body), err
 	}
 
-	yamlBlock := rest[:idx]
-	body := rest[idx+4:] // skip "\n---"
-	// Skip optional newline after closing ---
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("\n"+block), &doc); err != nil {
+		line, msg := yamlErrorPosition(err)
+		return nil, []byte(body), &FrontmatterError{Line: line, Msg: msg}
+	}
+	if len(doc.Content) == 0 {
+		return nil, []byte(body), nil
+	}
+	fields := mappingNode(&doc)
+	if fields == nil {
+		return nil, []byte(body), &FrontmatterError{Line: doc.Content[0].Line, Msg: "expected fields (key: value)"}
+	}
+	return fields, []byte(body), nil
+}
+
+// cutFrontmatter splits s at a --- or +++ delimited block on its first
+// lines, returning the block, the body after it, and the delimiter.
+// The delimiter is empty if s doesn't start with a closed block.
+func cutFrontmatter(s string) (block, body, delim string) {
+	for _, d := range []string{"---", "+++"} {
+		var rest string
+		switch {
+		case strings.HasPrefix(s, d+"\n"):
+			rest = s[4:]
+		case strings.HasPrefix(s, d+"\r\n"):
+			rest = s[5:]
+		default:
+			continue
+		}
+
+		// Find closing delimiter
+		idx := strings.Index(rest, "\n"+d)
+		if idx < 0 {
+			return "", s, ""
+		}
+
+		body = rest[idx+4:] // skip "\n---"
+		// Skip optional newline after closing delimiter
+		if len(body) > 0 && body[0] == '\n' {
+			body = body[1:]
+		} else if strings.HasPrefix(body, "\r\n") {
+			body = body[2:]
+		}
+		return rest[:idx], body, d
+	}
+	return "", s, ""
+}
+
+// reJSONFrontmatter matches a JSON object at the start of a file. Requiring
+// a key (or an empty object) keeps a leading {{< shortcode >}} out.
+var reJSONFrontmatter = regexp.MustCompile(`^\{\s*["}]`)
+
+// splitJSONFrontmatter parses the JSON object at the start of content.
+func splitJSONFrontmatter(content []byte) (*yaml.Node, []byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var value map[string]any
+	if err := dec.Decode(&value); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, _ := sourcePosition(content, int(syntaxErr.Offset))
+			return nil, content, &FrontmatterError{Line: line, Msg: syntaxErr.Error()}
+		}
+		return nil, content, &FrontmatterError{Line: 1, Msg: "JSON frontmatter is never closed"}
+	}
+	end := int(dec.InputOffset())
+	block, body := content[:end], content[end:]
 	if len(body) > 0 && body[0] == '\n' {
 		body = body[1:]
-	} else if strings.HasPrefix(body, "\r\n") {
+	} else if bytes.HasPrefix(body, []byte("\r\n")) {
 		body = body[2:]
 	}
 
+	// JSON is nearly always valid YAML, which keeps line numbers for type
+	// errors; otherwise (say, a "\/" escape) convert the decoded values
+	var doc yaml.Node
+	if err := yaml.Unmarshal(block, &doc); err == nil {
+		if fields := mappingNode(&doc); fields != nil {
+			return fields, body, nil
+		}
+	}
+	return valueNode(value, 1), body, nil
+}
+
+// parseTOMLFrontmatter parses a TOML block. TOML values have no positions,
+// so each top-level key is given the line it's defined on, for type errors.
+func parseTOMLFrontmatter(block string) (*yaml.Node, error) {
+	var value map[string]any
+	if _, err := toml.Decode(block, &value); err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			return nil, &FrontmatterError{Line: perr.Position.Line, Msg: perr.Message}
+		}
+		return nil, &FrontmatterError{Msg: err.Error()}
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	fields := valueNode(value, 1)
+	lines := tomlKeyLines(block)
+	for i := 0; i+1 < len(fields.Content); i += 2 {
+		if line, ok := lines[fields.Content[i].Value]; ok {
+			setNodeLine(fields.Content[i], line)
+			setNodeLine(fields.Content[i+1], line)
+		}
+	}
+	return fields, nil
+}
+
+// tomlKeyLines maps each top-level key in a TOML document to the 1-based line
+// that defines it: a key = value line before any table, or a [table] header.
+func tomlKeyLines(block string) map[string]int {
+	lines := make(map[string]int)
+	inTable, inString := false, ""
+	for i, line := range strings.Split(block, "\n") {
+		// Skip the inside of multi-line strings
+		if inString != "" {
+			if strings.Count(line, inString)%2 == 1 {
+				inString = ""
+			}
+			continue
+		}
+		for _, q := range []string{`"""`, `'''`} {
+			if strings.Count(line, q)%2 == 1 {
+				inString = q
+			}
+		}
+
+		line = strings.TrimSpace(line)
+		var key string
+		switch {
+		case strings.HasPrefix(line, "["):
+			inTable = true
+			key, _, _ = strings.Cut(strings.TrimLeft(line, "["), "]")
+		case !inTable && !strings.HasPrefix(line, "#") && strings.Contains(line, "="):
+			key, _, _ = strings.Cut(line, "=")
+		default:
+			continue
+		}
+		key, _, _ = strings.Cut(strings.TrimSpace(key), ".")
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		if _, ok := lines[key]; !ok {
+			lines[key] = i + 1
+		}
+	}
+	return lines
+}
+
+// setNodeLine sets line on n and everything inside it.
+func setNodeLine(n *yaml.Node, line int) {
+	n.Line = line
+	for _, c := range n.Content {
+		setNodeLine(c, line)
+	}
+}
+
+// valueNode converts a value decoded from TOML or JSON to a YAML node with
+// the same meaning, so it decodes into Frontmatter as the YAML would.
+// Dates become !!timestamp scalars written as they'd appear in YAML.
+func valueNode(v any, line int) *yaml.Node {
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Line: line}
+	}
+	switch v := v.(type) {
+	case nil:
+		return scalar("!!null", "null")
+	case string:
+		return scalar("!!str", v)
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v))
+	case int64:
+		return scalar("!!int", strconv.FormatInt(v, 10))
+	case float64:
+		return scalar("!!float", strconv.FormatFloat(v, 'g', -1, 64))
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return scalar("!!int", v.String())
+		}
+		return scalar("!!float", v.String())
+	case time.Time:
+		// TOML dates and times without an offset get these locations
+		switch v.Location().String() {
+		case "date-local":
+			return scalar("!!timestamp", v.Format("2006-01-02"))
+		case "datetime-local":
+			return scalar("!!timestamp", v.Format("2006-01-02T15:04:05.999999999"))
+		case "time-local":
+			return scalar("!!str", v.Format("15:04:05.999999999"))
+		}
+		return scalar("!!timestamp", v.Format(time.RFC3339Nano))
+	case map[string]any:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n.Content = append(n.Content, scalar("!!str", k), valueNode(v[k], line))
+		}
+		return n
+	case []map[string]any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line}
+		for _, item := range v {
+			n.Content = append(n.Content, valueNode(item, line))
+		}
+		return n
+	case []any:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line}
+		for _, item := range v {
+			n.Content = append(n.Content, valueNode(item, line))
+		}
+		return n
+	}
+	return scalar("!!str", fmt.Sprint(v))
+}
+
+// mappingNode unwraps a parsed YAML document, returning nil unless it holds a mapping.
+func mappingNode(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// knownFrontmatterKeys are the fields decoded into Frontmatter; everything
+// else ends up in Extra.
+var knownFrontmatterKeys = []string{
+	"title", "description", "url", "layout", "date", "draft", "publish_date", "expiry_date", "lastmod",
+	"weight", "nav_title", "nav_hidden", "nav_order", "toc", "toc_levels", "paginate", "cascade",
+}
+
+// decodeFrontmatter fills a Frontmatter from a YAML mapping node. A field of
+// the wrong type is left unset and reported as a *FrontmatterError; the
+// others are still decoded.
+func decodeFrontmatter(fields *yaml.Node) (Frontmatter, error) {
+	var fm Frontmatter
+	if fields == nil {
+		return fm, nil
+	}
+
 	// Parse known fields
-	_ = yaml.Unmarshal([]byte(yamlBlock), &fm)
+	var fmErr error
+	if err := fields.Decode(&fm); err != nil {
+		line, msg := yamlErrorPosition(err)
+		fmErr = &FrontmatterError{Line: line, Msg: msg}
+	}
 
 	// Parse all fields into a map for extras
 	var raw map[string]any
-	_ = yaml.Unmarshal([]byte(yamlBlock), &raw)
-	if raw != nil {
-		// Remove known fields, keep the rest as Extra
-		delete(raw, "title")
-		delete(raw, "description")
-		delete(raw, "url")
-		delete(raw, "layout")
-		delete(raw, "date")
-		delete(raw, "draft")
-		if len(raw) > 0 {
-			fm.Extra = raw
-		}
+	_ = fields.Decode(&raw)
+	// Remove known fields, keep the rest as Extra
+	for _, k := range knownFrontmatterKeys {
+		delete(raw, k)
+	}
+	if len(raw) > 0 {
+		fm.Extra = raw
 	}
+	return fm, fmErr
+}
 
-	return fm, []byte(body)
+// mergeFrontmatter returns the mapping defaults overlaid with fields, key by
+// key. Neither node is modified.
+func mergeFrontmatter(defaults, fields *yaml.Node) *yaml.Node {
+	if defaults == nil {
+		return fields
+	}
+	if fields == nil {
+		return defaults
+	}
+	set := make(map[string]bool, len(fields.Content)/2)
+	for i := 0; i+1 < len(fields.Content); i += 2 {
+		set[fields.Content[i].Value] = true
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(defaults.Content); i += 2 {
+		if !set[defaults.Content[i].Value] {
+			merged.Content = append(merged.Content, defaults.Content[i], defaults.Content[i+1])
+		}
+	}
+	merged.Content = append(merged.Content, fields.Content...)
+	return merged
 }
 
 // reNumPrefixFM matches leading digits followed by a hyphen: "01-", "1-", "001-"
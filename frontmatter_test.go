@@ -1,12 +1,20 @@
 package main
 
 import (
+	"errors"
+	"reflect"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseFrontmatter(t *testing.T) {
 	input := []byte("---\ntitle: Hello\ndescription: World\n---\nBody here")
-	fm, body := ParseFrontmatter(input)
+	fm, body, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Title != "Hello" {
 		t.Errorf("Title = %q, want Hello", fm.Title)
@@ -21,7 +29,10 @@ func TestParseFrontmatter(t *testing.T) {
 
 func TestParseFrontmatterNoDelimiter(t *testing.T) {
 	input := []byte("Just markdown, no frontmatter")
-	fm, body := ParseFrontmatter(input)
+	fm, body, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Title != "" {
 		t.Errorf("Title = %q, want empty", fm.Title)
@@ -33,7 +44,10 @@ func TestParseFrontmatterNoDelimiter(t *testing.T) {
 
 func TestParseFrontmatterExtras(t *testing.T) {
 	input := []byte("---\ntitle: Test\ncustom_key: custom_val\n---\nBody")
-	fm, _ := ParseFrontmatter(input)
+	fm, _, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Extra == nil {
 		t.Fatal("Extra should not be nil")
@@ -45,7 +59,10 @@ func TestParseFrontmatterExtras(t *testing.T) {
 
 func TestParseFrontmatterDateAndDraft(t *testing.T) {
 	input := []byte("---\ntitle: My Post\ndate: 2026-03-18\ndraft: true\n---\nBody")
-	fm, _ := ParseFrontmatter(input)
+	fm, _, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Date != "2026-03-18" {
 		t.Errorf("Date = %q, want 2026-03-18", fm.Date)
@@ -64,6 +81,21 @@ func TestParseFrontmatterDateAndDraft(t *testing.T) {
 	}
 }
 
+func TestParseFrontmatterNavFields(t *testing.T) {
+	input := []byte("---\ntitle: A Very Long Title\nweight: 10\nnav_title: Short\nnav_hidden: true\nnav_order: title\nicon: box\n---\n")
+	fm, _, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fm.Weight != 10 || fm.NavTitle != "Short" || !fm.NavHidden || fm.NavOrder != "title" {
+		t.Errorf("nav fields = %d %q %v %q, want 10 Short true title", fm.Weight, fm.NavTitle, fm.NavHidden, fm.NavOrder)
+	}
+	if len(fm.Extra) != 1 || fm.Extra["icon"] != "box" {
+		t.Errorf("Extra = %v, want only icon", fm.Extra)
+	}
+}
+
 func TestParseDateFormats(t *testing.T) {
 	tests := []struct {
 		input string
@@ -74,6 +106,10 @@ func TestParseDateFormats(t *testing.T) {
 		{"2026-03-18 14:30:00", true},
 		{"2026-03-18T14:30", true},
 		{"2026-03-18T14:30:00", true},
+		{"2026-03-18T14:30:00Z", true},
+		{"2026-03-18T14:30:00+02:00", true},
+		{"2026-03-18T14:30:00.5-05:00", true},
+		{"2026-03-18 14:30:00+02:00", true},
 		{"18-03-2026", false},
 		{"March 18, 2026", false},
 		{"", false},
@@ -87,6 +123,25 @@ func TestParseDateFormats(t *testing.T) {
 	}
 }
 
+func TestParseDateIn(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2026-03-18", "2026-03-18T00:00:00+09:00"},
+		{"2026-03-18 14:30", "2026-03-18T14:30:00+09:00"},
+		{"2026-03-18T14:30:00Z", "2026-03-18T23:30:00+09:00"},
+		{"2026-03-18T20:00:00-05:00", "2026-03-19T10:00:00+09:00"},
+	}
+	for _, tt := range tests {
+		got, ok := parseDateIn(tt.input, tokyo)
+		if !ok || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("parseDateIn(%q) = %s, %v; want %s", tt.input, got.Format(time.RFC3339), ok, tt.want)
+		}
+	}
+}
+
 func TestParseDateTimestampOrdering(t *testing.T) {
 	// String comparison of ISO timestamps must sort correctly
 	a := "2026-02-25 10:00"
@@ -105,7 +160,10 @@ func TestParseDateTimestampOrdering(t *testing.T) {
 
 func TestParseFrontmatterCRLF(t *testing.T) {
 	input := []byte("---\r\ntitle: CRLF\r\n---\r\nBody")
-	fm, body := ParseFrontmatter(input)
+	fm, body, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Title != "CRLF" {
 		t.Errorf("Title = %q, want CRLF", fm.Title)
@@ -117,7 +175,10 @@ func TestParseFrontmatterCRLF(t *testing.T) {
 
 func TestParseFrontmatterUnclosed(t *testing.T) {
 	input := []byte("---\ntitle: Unclosed\nno closing delimiter")
-	fm, body := ParseFrontmatter(input)
+	fm, body, err := ParseFrontmatter(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if fm.Title != "" {
 		t.Errorf("Title = %q, want empty (unclosed)", fm.Title)
@@ -166,3 +227,144 @@ func TestTitleFromDir(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFrontmatterWithDefaults(t *testing.T) {
+	defaults, _, _ := splitFrontmatter([]byte("---\nlayout: post\ntoc: false\nauthor: Ada\n---\n"))
+	fm, body, err := parseFrontmatter([]byte("---\ntitle: Hello\ntoc: true\ncascade:\n  layout: wide\n---\nBody"), defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fm.Title != "Hello" || fm.Layout != "post" {
+		t.Errorf("Title, Layout = %q, %q; want Hello, post", fm.Title, fm.Layout)
+	}
+	if fm.TOC == nil || !*fm.TOC {
+		t.Error("page toc: true should win over the default")
+	}
+	if fm.Extra["author"] != "Ada" {
+		t.Errorf("Extra[author] = %v, want Ada", fm.Extra["author"])
+	}
+	if _, ok := fm.Extra["cascade"]; ok {
+		t.Error("cascade should not be in Extra")
+	}
+	if string(body) != "Body" {
+		t.Errorf("Body = %q, want Body", body)
+	}
+
+	// Defaults apply to pages without frontmatter too
+	fm, _, _ = parseFrontmatter([]byte("# Plain"), defaults)
+	if fm.Layout != "post" {
+		t.Errorf("Layout = %q, want post", fm.Layout)
+	}
+}
+
+func TestParseFrontmatterErrors(t *testing.T) {
+	tests := []struct {
+		name, input string
+		line        int // 0: any
+	}{
+		{"unclosed flow sequence", "---\ntitle: Post\ndate: [2026-01-01\n---\nBody", 0},
+		{"tab indent", "---\ntitle: Post\n\tdraft: true\n---\nBody", 0},
+		{"bad mapping", "---\ntitle: Post\ndraft: true: false\n---\nBody", 3},
+		{"wrong type", "---\ntitle: Post\n\ndraft: maybe\n---\nBody", 4},
+		{"not fields", "---\njust a sentence\n---\nBody", 2},
+		{"crlf", "---\r\ntitle: Post\r\nweight: heavy\r\n---\r\nBody", 3},
+	}
+	for _, tt := range tests {
+		_, body, err := ParseFrontmatter([]byte(tt.input))
+		var fmErr *FrontmatterError
+		if !errors.As(err, &fmErr) {
+			t.Errorf("%s: got %v, want a FrontmatterError", tt.name, err)
+			continue
+		}
+		// yaml.v3 places some syntax errors at the start of the enclosing
+		// block rather than the offending character; only check exact lines
+		if tt.line != 0 && fmErr.Line != tt.line {
+			t.Errorf("%s: error %q on line %d, want %d", tt.name, err, fmErr.Line, tt.line)
+		}
+		if string(body) != "Body" {
+			t.Errorf("%s: Body = %q, want Body", tt.name, body)
+		}
+	}
+}
+
+func TestParseFrontmatterWrongTypeKeepsOtherFields(t *testing.T) {
+	fm, _, err := ParseFrontmatter([]byte("---\ntitle: Post\nweight: heavy\nstatus: beta\n---\n"))
+	if err == nil {
+		t.Fatal("expected an error for weight: heavy")
+	}
+	if fm.Title != "Post" || fm.Weight != 0 || fm.Extra["status"] != "beta" {
+		t.Errorf("got %+v, want the valid fields decoded", fm)
+	}
+}
+
+func TestParseFrontmatterFormatsMatch(t *testing.T) {
+	inputs := map[string]string{
+		"yaml": "---\ntitle: Hello\ndate: 2026-03-18\ndraft: true\nweight: 3\ntoc: false\ntags: [a, b]\nstatus: beta\n---\nBody",
+		"toml": "+++\ntitle = \"Hello\"\ndate = 2026-03-18\ndraft = true\nweight = 3\ntoc = false\ntags = [\"a\", \"b\"]\nstatus = \"beta\"\n+++\nBody",
+		"json": "{\n  \"title\": \"Hello\",\n  \"date\": \"2026-03-18\",\n  \"draft\": true,\n  \"weight\": 3,\n  \"toc\": false,\n  \"tags\": [\"a\", \"b\"],\n  \"status\": \"beta\"\n}\nBody",
+	}
+	want, _, _ := ParseFrontmatter([]byte(inputs["yaml"]))
+	for format, input := range inputs {
+		fm, body, err := ParseFrontmatter([]byte(input))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(fm, want) {
+			t.Errorf("%s: got %+v, want %+v", format, fm, want)
+		}
+		if string(body) != "Body" {
+			t.Errorf("%s: Body = %q, want Body", format, body)
+		}
+	}
+	if want.Title != "Hello" || want.Date != "2026-03-18" || !want.Draft || want.Weight != 3 || want.Extra["status"] != "beta" {
+		t.Errorf("unexpected YAML frontmatter %+v", want)
+	}
+}
+
+func TestParseFrontmatterTOMLTables(t *testing.T) {
+	input := "+++\ntitle = \"Post\"\nsummary = \"\"\"\nweight = 1\n\"\"\"\n[cascade]\nlayout = \"post\"\n+++\n"
+	fields, _, err := splitFrontmatter([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm, _ := decodeFrontmatter(fields)
+	if fm.Weight != 0 || fm.Extra["summary"] != "weight = 1\n" {
+		t.Errorf("multi-line string parsed as a key: %+v", fm)
+	}
+	if cascade, _ := cascadeNode(fields); cascade == nil || cascade.Kind != yaml.MappingNode {
+		t.Error("expected [cascade] table as a mapping")
+	}
+}
+
+func TestParseFrontmatterFormatErrors(t *testing.T) {
+	tests := []struct {
+		name, input string
+		line        int
+	}{
+		{"toml syntax", "+++\ntitle = \"A\"\nweight = \n+++\nBody", 3},
+		{"toml type", "+++\ntitle = \"A\"\n\nweight = \"heavy\"\n+++\nBody", 4},
+		{"toml table type", "+++\ntitle = \"A\"\n[toc]\nlevels = 2\n+++\nBody", 3},
+		{"json syntax", "{\n  \"title\": \"A\",\n  \"weight\": }\nBody", 3},
+		{"json type", "{\n  \"title\": \"A\",\n  \"draft\": \"maybe\"\n}\nBody", 3},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseFrontmatter([]byte(tt.input))
+		var fmErr *FrontmatterError
+		if !errors.As(err, &fmErr) {
+			t.Errorf("%s: got %v, want a FrontmatterError", tt.name, err)
+			continue
+		}
+		if fmErr.Line != tt.line {
+			t.Errorf("%s: error %q on line %d, want %d", tt.name, err, fmErr.Line, tt.line)
+		}
+	}
+}
+
+func TestParseFrontmatterLeadingShortcodeIsNotJSON(t *testing.T) {
+	input := []byte("{{< note >}}Hi{{< /note >}}\n")
+	fm, body, err := ParseFrontmatter(input)
+	if err != nil || fm.Extra != nil || string(body) != string(input) {
+		t.Errorf("got %+v, %q, %v; want the whole file as body", fm, body, err)
+	}
+}
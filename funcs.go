@@ -0,0 +1,562 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// templateFuncs builds the function library shared by layouts and
+// shortcodes. Functions read the fields when they run, so md can be set
+// after templates are parsed, once pages are known.
+type templateFuncs struct {
+	basePath string           // Site base_path without trailing slash
+	siteURL  string           // Absolute site URL including base path, without trailing slash
+	md       markdownRenderer // Renders markdownify input; nil uses a plain pipeline
+	loc      *time.Location   // Time zone dates are read and shown in; nil is UTC
+}
+
+func newTemplateFuncs(cfg Config) *templateFuncs {
+	loc, _ := cfg.Location() // An invalid timezone fails the build before templates run
+	return &templateFuncs{
+		basePath: strings.TrimRight(cfg.BasePath, "/"),
+		siteURL:  strings.TrimRight(cfg.SiteURL, "/"),
+		loc:      loc,
+	}
+}
+
+// funcMap returns the template functions. A nil receiver gives functions
+// for a site at the root with no site_url.
+func (f *templateFuncs) funcMap() template.FuncMap {
+	if f == nil {
+		f = &templateFuncs{}
+	}
+	return template.FuncMap{
+		"safeHTML":    func(s string) template.HTML { return template.HTML(s) },
+		"linkIcon":    func(name string) template.HTML { return template.HTML(linkIcon(name)) },
+		"navURL":      navURL,
+		"formatDate":  f.formatDate,
+		"markdownify": f.markdownify,
+		"relURL":      f.relURL,
+		"absURL":      f.absURL,
+		"where":       wherePages,
+		"sortBy":      sortPages,
+		"groupBy":     groupPages,
+		"first":       firstItem,
+		"limit":       limitItems,
+		"truncate":    truncate,
+		"plainify":    plainify,
+		"jsonify":     jsonify,
+		"dict":        dict,
+		"slice":       func(items ...any) []any { return items },
+		"default":     defaultValue,
+		"urlize":      func(s string) string { return slugify(s) },
+	}
+}
+
+// navURL resolves a config URL against basePath: absolute URLs, mailto:,
+// and fragments are kept; anything else is treated as site-relative.
+func navURL(basePath, url string) string {
+	if url == "" {
+		return ""
+	}
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "mailto:") || strings.HasPrefix(url, "#") {
+		return url
+	}
+	basePath = strings.TrimRight(basePath, "/")
+	if strings.HasPrefix(url, "/") {
+		return basePath + url
+	}
+	if basePath == "" {
+		return "/" + url
+	}
+	return basePath + "/" + url
+}
+
+// relURL makes a site-relative path ("/img/a.png" or "img/a.png") absolute
+// from the domain root, adding base_path. Absolute URLs are unchanged.
+func (f *templateFuncs) relURL(u string) string {
+	if f.basePath != "" && (u == f.basePath || strings.HasPrefix(u, f.basePath+"/")) {
+		return u
+	}
+	return navURL(f.basePath, u)
+}
+
+// absURL is relURL with site_url in front. Without site_url it falls back
+// to relURL.
+func (f *templateFuncs) absURL(u string) string {
+	rel := f.relURL(u)
+	if f.siteURL == "" || !strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, "//") {
+		return rel
+	}
+	// siteURL already ends with base_path
+	return f.siteURL + strings.TrimPrefix(rel, f.basePath)
+}
+
+// markdownify renders markdown to HTML. Output that is a single paragraph
+// is unwrapped so it can be used inline.
+func (f *templateFuncs) markdownify(s any) (template.HTML, error) {
+	source := []byte(toText(s))
+	var out []byte
+	var err error
+	if f.md != nil {
+		out, err = f.md.render(source)
+	} else {
+		out, err = RenderMarkdown(source)
+	}
+	if err != nil {
+		return "", fmt.Errorf("markdownify: %w", err)
+	}
+	h := strings.TrimSpace(string(out))
+	if inner, ok := strings.CutPrefix(h, "<p>"); ok && strings.HasSuffix(inner, "</p>") && !strings.Contains(inner, "<p>") {
+		h = strings.TrimSuffix(inner, "</p>")
+	}
+	return template.HTML(h), nil
+}
+
+// formatDate renders a date as "January 2, 2006", or with a Go time layout
+// and locale when given: formatDate .Date "2 January 2006" "de". Values that
+// aren't dates are returned unchanged.
+func (f *templateFuncs) formatDate(v any, args ...string) (string, error) {
+	layout, locale := "January 2, 2006", ""
+	switch len(args) {
+	case 0:
+	case 1:
+		layout = args[0]
+	case 2:
+		layout, locale = args[0], args[1]
+	default:
+		return "", fmt.Errorf("formatDate takes a date, layout, and locale, got %d arguments", len(args)+1)
+	}
+	names, ok := lookupDateNames(locale)
+	if !ok {
+		return "", fmt.Errorf("formatDate: unknown locale %q (known: %s)", locale, strings.Join(dateLocales(), ", "))
+	}
+	t, ok := f.date(v)
+	if !ok {
+		return toText(v), nil
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+	return localizeDate(t, layout, names), nil
+}
+
+// date converts a template value to a time in the site time zone. Strings
+// are parsed as frontmatter dates. A nil *time.Time gives the zero time.
+func (f *templateFuncs) date(v any) (time.Time, bool) {
+	loc := f.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	switch v := v.(type) {
+	case time.Time:
+		return v.In(loc), true
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, true
+		}
+		return v.In(loc), true
+	}
+	return parseDateIn(toText(v), loc)
+}
+
+// dateNames are the month and weekday names of a locale.
+type dateNames struct {
+	months, shortMonths [12]string
+	days, shortDays     [7]string // Sunday first, like time.Weekday
+}
+
+// localeDateNames holds the locales formatDate knows besides English.
+var localeDateNames = map[string]*dateNames{
+	"de": {
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	},
+	"es": {
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+	"fr": {
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+	"it": {
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	},
+	"nl": {
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+	"pt": {
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		shortDays:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	},
+}
+
+// lookupDateNames finds the names for a locale such as "de" or "pt-BR"; only
+// the language is used. English, or no locale, gives nil.
+func lookupDateNames(locale string) (*dateNames, bool) {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	if lang == "" || lang == "en" {
+		return nil, true
+	}
+	names, ok := localeDateNames[lang]
+	return names, ok
+}
+
+// dateLocales lists the locales formatDate knows, sorted.
+func dateLocales() []string {
+	locales := []string{"en"}
+	for lang := range localeDateNames {
+		locales = append(locales, lang)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// localizeDate formats t with layout, putting month and weekday names in
+// the given locale; nil names give Go's English ones.
+func localizeDate(t time.Time, layout string, names *dateNames) string {
+	if names == nil {
+		return t.Format(layout)
+	}
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(layout); {
+		var name string
+		var n int
+		// Longest first, matching how time.Format reads the layout
+		switch rest := layout[i:]; {
+		case strings.HasPrefix(rest, "January"):
+			name, n = names.months[t.Month()-1], len("January")
+		case strings.HasPrefix(rest, "Jan"):
+			name, n = names.shortMonths[t.Month()-1], len("Jan")
+		case strings.HasPrefix(rest, "Monday"):
+			name, n = names.days[t.Weekday()], len("Monday")
+		case strings.HasPrefix(rest, "Mon"):
+			name, n = names.shortDays[t.Weekday()], len("Mon")
+		default:
+			i++
+			continue
+		}
+		b.WriteString(t.Format(layout[start:i]))
+		b.WriteString(name)
+		i += n
+		start = i
+	}
+	b.WriteString(t.Format(layout[start:]))
+	return b.String()
+}
+
+// PageGroup is one group of pages returned by groupBy.
+type PageGroup struct {
+	Key   string
+	Pages []PageMeta
+}
+
+// pageField looks up a PageMeta field by name, ignoring case. "Extra.key"
+// (or "extra.key") reads a custom frontmatter field. Missing fields are nil.
+func pageField(p PageMeta, key string) any {
+	if name, ok := cutPrefixFold(key, "extra."); ok {
+		return p.Extra[name]
+	}
+	v := reflect.ValueOf(p).FieldByNameFunc(func(name string) bool {
+		return strings.EqualFold(name, key)
+	})
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// wherePages keeps pages whose field matches a value:
+//
+//	where .Pages "Section" "guide"
+//	where .Pages "Date" ">=" "2026-01-01"
+//	where .Pages "Extra.status" "in" (slice "draft" "review")
+//
+// Operators are ==, !=, <, <=, >, >=, and in. Values compare as numbers
+// when both sides are numeric, otherwise as text.
+func wherePages(pages []PageMeta, key string, args ...any) ([]PageMeta, error) {
+	op, want := "==", any(nil)
+	switch len(args) {
+	case 1:
+		want = args[0]
+	case 2:
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("where: operator must be a string, got %T", args[0])
+		}
+		op, want = s, args[1]
+	default:
+		return nil, fmt.Errorf("where: expected a value or an operator and a value, got %d arguments", len(args))
+	}
+
+	var match func(any) bool
+	switch op {
+	case "==", "=", "eq":
+		match = func(v any) bool { return compareValues(v, want) == 0 }
+	case "!=", "<>", "ne":
+		match = func(v any) bool { return compareValues(v, want) != 0 }
+	case "<", "lt":
+		match = func(v any) bool { return compareValues(v, want) < 0 }
+	case "<=", "le":
+		match = func(v any) bool { return compareValues(v, want) <= 0 }
+	case ">", "gt":
+		match = func(v any) bool { return compareValues(v, want) > 0 }
+	case ">=", "ge":
+		match = func(v any) bool { return compareValues(v, want) >= 0 }
+	case "in":
+		list := reflect.ValueOf(want)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return nil, fmt.Errorf("where: \"in\" needs a list, got %T", want)
+		}
+		match = func(v any) bool {
+			for i := 0; i < list.Len(); i++ {
+				if compareValues(v, list.Index(i).Interface()) == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, fmt.Errorf("where: unknown operator %q", op)
+	}
+
+	var result []PageMeta
+	for _, p := range pages {
+		if match(pageField(p, key)) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// sortPages returns a copy of pages sorted by a field, "asc" (default) or
+// "desc". Equal pages keep their order.
+func sortPages(pages []PageMeta, key string, order ...string) ([]PageMeta, error) {
+	desc := false
+	if len(order) > 0 {
+		switch strings.ToLower(order[0]) {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, fmt.Errorf("sortBy: order must be \"asc\" or \"desc\", got %q", order[0])
+		}
+	}
+	sorted := append([]PageMeta(nil), pages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c := compareValues(pageField(sorted[i], key), pageField(sorted[j], key))
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return sorted, nil
+}
+
+// groupPages groups pages by a field's value, in order of first appearance.
+// Pages without the field are grouped under "".
+func groupPages(pages []PageMeta, key string) []PageGroup {
+	var groups []PageGroup
+	index := make(map[string]int)
+	for _, p := range pages {
+		k := toText(pageField(p, key))
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, PageGroup{Key: k})
+		}
+		groups[i].Pages = append(groups[i].Pages, p)
+	}
+	return groups
+}
+
+// compareValues orders two values: numerically when both are numbers (or
+// numeric strings), by time for two times or date strings, otherwise as
+// text. nil sorts as "".
+func compareValues(a, b any) int {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	// Date strings compare as times, whatever their format
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			ta, okA := ParseDate(sa)
+			tb, okB := ParseDate(sb)
+			if okA && okB {
+				return ta.Compare(tb)
+			}
+		}
+	}
+	if fa, ok := toNumber(a); ok {
+		if fb, ok := toNumber(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(toText(a), toText(b))
+}
+
+func toNumber(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toText formats a template value as text; nil is "".
+func toText(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// firstItem returns the first element of a list, or nil if it is empty.
+func firstItem(list any) (any, error) {
+	v := reflect.ValueOf(list)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("first: expected a list, got %T", list)
+	}
+	if v.Len() == 0 {
+		return nil, nil
+	}
+	return v.Index(0).Interface(), nil
+}
+
+// limitItems returns at most the first n elements of a list.
+func limitItems(n int, list any) (any, error) {
+	v := reflect.ValueOf(list)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("limit: expected a list, got %T", list)
+	}
+	n = max(0, min(n, v.Len()))
+	return v.Slice(0, n).Interface(), nil
+}
+
+// truncate shortens text to at most n characters, breaking at a word when
+// it can and ending with "…". An n of 0 or less leaves nothing.
+func truncate(n int, s any) string {
+	if n <= 0 {
+		return ""
+	}
+	text := toText(s)
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	cut := n
+	for i := n; i >= n/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// plainify strips HTML tags and decodes entities, leaving plain text.
+func plainify(s any) string {
+	return html.UnescapeString(reHTMLTag.ReplaceAllString(toText(s), ""))
+}
+
+// jsonify encodes v as JSON, for use in scripts and data attributes.
+func jsonify(v any) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("jsonify: %w", err)
+	}
+	return template.JS(data), nil
+}
+
+// dict builds a map from alternating keys and values, for passing several
+// values to a template: dict "title" .Title "url" .URL
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected key-value pairs, got %d arguments", len(pairs))
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is %T, not a string", pairs[i], pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// defaultValue returns v, or def when v is missing or empty (nil, "",
+// false, 0, or an empty list or map): .Get "label" | default "Read more"
+func defaultValue(def any, v ...any) any {
+	if len(v) == 0 || v[0] == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v[0])
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if rv.Len() == 0 {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v[0]
+}
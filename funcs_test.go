@@ -0,0 +1,183 @@
+package main
+
+import (
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPages() []PageMeta {
+	return []PageMeta{
+		{Title: "Intro", URL: "/guide/intro/", Date: "2026-01-05", Section: "guide", Extra: map[string]any{"status": "done", "order": 2}},
+		{Title: "Setup", URL: "/guide/setup/", Date: "2026-03-01", Section: "guide", Extra: map[string]any{"status": "draft", "order": 10}},
+		{Title: "About", URL: "/about/", Extra: map[string]any{"order": 1}},
+	}
+}
+
+func pageTitles(pages []PageMeta) string {
+	var titles []string
+	for _, p := range pages {
+		titles = append(titles, p.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestWherePages(t *testing.T) {
+	tests := []struct {
+		key  string
+		args []any
+		want string
+	}{
+		{"Section", []any{"guide"}, "Intro,Setup"},
+		{"section", []any{"!=", "guide"}, "About"},
+		{"Date", []any{">=", "2026-02-01"}, "Setup"},
+		{"Extra.status", []any{"in", []any{"draft", "review"}}, "Setup"},
+		{"Extra.order", []any{">", 1}, "Intro,Setup"},
+	}
+	for _, tt := range tests {
+		got, err := wherePages(testPages(), tt.key, tt.args...)
+		if err != nil {
+			t.Fatalf("where %s %v: %v", tt.key, tt.args, err)
+		}
+		if pageTitles(got) != tt.want {
+			t.Errorf("where %s %v = %s, want %s", tt.key, tt.args, pageTitles(got), tt.want)
+		}
+	}
+
+	if _, err := wherePages(testPages(), "Title", "~", "x"); err == nil {
+		t.Error("expected error for unknown operator")
+	}
+}
+
+func TestSortAndGroupPages(t *testing.T) {
+	sorted, err := sortPages(testPages(), "Extra.order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Numeric, not text, ordering: 1, 2, 10
+	if got := pageTitles(sorted); got != "About,Intro,Setup" {
+		t.Errorf("sortBy Extra.order = %s", got)
+	}
+	sorted, _ = sortPages(testPages(), "Title", "desc")
+	if got := pageTitles(sorted); got != "Setup,Intro,About" {
+		t.Errorf("sortBy Title desc = %s", got)
+	}
+
+	// Dates compare as times, not text: "2026-03-01 09:00" is after "2026-03-01T08:00:00Z"
+	pages := testPages()
+	pages[0].Date, pages[1].Date = "2026-03-01 09:00", "2026-03-01T08:00:00Z"
+	sorted, _ = sortPages(pages[:2], "Date")
+	if got := pageTitles(sorted); got != "Setup,Intro" {
+		t.Errorf("sortBy Date = %s", got)
+	}
+
+	groups := groupPages(testPages(), "Section")
+	if len(groups) != 2 || groups[0].Key != "guide" || pageTitles(groups[0].Pages) != "Intro,Setup" || groups[1].Key != "" {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	funcs := newTemplateFuncs(Config{BasePath: "/docs/", SiteURL: "https://example.com/docs/"})
+	src := `{{ relURL "/img/a.png" }}|{{ relURL "/docs/x/" }}|{{ absURL "guide/" }}|` +
+		`{{ markdownify "**bold** text" }}|{{ (first .Pages).Title }}|{{ len (limit 2 .Pages) }}|` +
+		`{{ "a long sentence that goes on" | truncate 12 }}|{{ plainify "<b>A &amp; B</b>" }}|` +
+		`{{ with dict "k" "v" }}{{ .k }}{{ end }}|{{ index (slice 1 2) 1 }}|{{ "" | default "fallback" }}|` +
+		`{{ urlize "Hello, World!" }}|{{ formatDate "2026-03-18" "Jan 2006" }}|{{ formatDate "2026-03-18" }}|` +
+		`<script>var d = {{ jsonify (dict "a" 1) }};</script>`
+	tmpl, err := template.New("t").Funcs(funcs.funcMap()).Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ Pages []PageMeta }{testPages()}); err != nil {
+		t.Fatal(err)
+	}
+	want := `/docs/img/a.png|/docs/x/|https://example.com/docs/guide/|` +
+		`<strong>bold</strong> text|Intro|2|` +
+		`a long…|A &amp; B|` +
+		`v|2|fallback|` +
+		`hello-world|Mar 2026|March 18, 2026|` +
+		`<script>var d = {"a":1};</script>`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	f := &templateFuncs{loc: time.FixedZone("UTC+2", 2*60*60)}
+	date := time.Date(2026, 3, 18, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value any
+		args  []string
+		want  string
+	}{
+		{"2026-03-18", nil, "March 18, 2026"},
+		{date, nil, "March 19, 2026"}, // Shown in the site time zone
+		{"2026-03-18 09:00", []string{"Jan 2, 15:04"}, "Mar 18, 09:00"},
+		{"2026-03-18", []string{"Monday 2 January 2006", "de"}, "Mittwoch 18 März 2026"},
+		{"2026-03-18", []string{"Mon 2 Jan", "fr_FR"}, "mer. 18 mars"},
+		{"2026-03-18", []string{"2 January 2006", "pt-BR"}, "18 março 2026"},
+		{"2026-03-18", []string{"January 2", "en-GB"}, "March 18"},
+		{time.Time{}, nil, ""},
+		{"soon", nil, "soon"},
+	}
+	for _, tt := range tests {
+		got, err := f.formatDate(tt.value, tt.args...)
+		if err != nil || got != tt.want {
+			t.Errorf("formatDate(%v, %q) = %q, %v; want %q", tt.value, tt.args, got, err, tt.want)
+		}
+	}
+
+	if _, err := f.formatDate("2026-03-18", "2006", "xx"); err == nil || !strings.Contains(err.Error(), `unknown locale "xx"`) {
+		t.Errorf("err = %v, want unknown locale", err)
+	}
+}
+
+func TestAbsURLWithoutSiteURL(t *testing.T) {
+	funcs := newTemplateFuncs(Config{BasePath: "/docs"})
+	if got := funcs.absURL("/a/"); got != "/docs/a/" {
+		t.Errorf("absURL without site_url = %q, want /docs/a/", got)
+	}
+	if got := funcs.absURL("https://other.example/"); got != "https://other.example/" {
+		t.Errorf("absURL kept absolute URL as %q", got)
+	}
+}
+
+func TestBuildMarkdownifyRendersAsPage(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_layout.html"), `{{ .Content }}{{ markdownify "## Intro\n\n[B](b.md)" }}`)
+	writeTestFile(t, filepath.Join(src, "_shortcodes/md.html"), `{{ markdownify (index .Positional 0) }}`)
+	writeTestFile(t, filepath.Join(src, "guide/a.md"), "## Intro\n\n{{< md \"## Intro\" />}}\n")
+	writeTestFile(t, filepath.Join(src, "guide/b.md"), "# B\n")
+
+	dst := t.TempDir()
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	out := readTestFile(t, filepath.Join(dst, "guide", "a", "index.html"))
+	for _, want := range []string{`<h2 id="intro">`, `<h2 id="intro-1">`, `<h2 id="intro-2">`, `<a href="/guide/b/">B</a>`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n        int
+		in, want string
+	}{
+		{12, "a long sentence that goes on", "a long…"},
+		{40, "short", "short"},
+		{0, "anything", ""},
+		{-3, "anything", ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.in); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.in, got, tt.want)
+		}
+	}
+}
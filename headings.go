@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// headingIDs is a page-wide heading ID registry (goldmark's parser.IDs).
+// One instance is shared by every markdown render for a page — block
+// shortcode inner content as well as the body — so IDs stay unique across
+// the whole page. Explicit {#id} attributes are reserved as they are seen.
+type headingIDs struct {
+	used  map[string]bool
+	clash []string // Explicit IDs that an earlier heading already had
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+// Link destinations in raw heading source: [text](dest)
+var reHeadingLinkDest = regexp.MustCompile(`\]\([^)]*\)`)
+
+// Generate slugs a heading (see slugify), ignoring link destinations.
+// Repeats get -1, -2, ... suffixes.
+func (h *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	slug := slugify(reHeadingLinkDest.ReplaceAllString(string(value), "]"))
+	if slug == "" {
+		slug = "heading"
+		if kind != ast.KindHeading {
+			slug = "id"
+		}
+	}
+	id := slug
+	for i := 1; h.used[id]; i++ {
+		id = fmt.Sprintf("%s-%d", slug, i)
+	}
+	h.used[id] = true
+	return []byte(id)
+}
+
+// slugify keeps letters and digits in any script, lowercased, turns runs
+// of whitespace, hyphens, and underscores into single hyphens, and drops
+// everything else.
+func slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// Put reserves an explicit ID. An ID that is already taken can't be given
+// to the heading that asked for it, so it is recorded for the page to report.
+func (h *headingIDs) Put(value []byte) {
+	id := string(value)
+	if h.used[id] {
+		h.clash = append(h.clash, id)
+	}
+	h.used[id] = true
+}
+
+// headingAnchorTransformer appends an empty permalink anchor to every
+// heading with an id. The "#" is drawn by CSS so it stays out of the
+// search index and the table of contents.
+type headingAnchorTransformer struct{}
+
+func (headingAnchorTransformer) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	pr, _ := pc.Get(pageRendererKey).(*pageRenderer)
+	if pr == nil || !pr.resolver.headingAnchors {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			anchor := ast.NewString([]byte(fmt.Sprintf(
+				`<a class="heading-anchor" href="#%s" aria-label="Permalink to this section"></a>`, html.EscapeString(attrString(id)))))
+			anchor.SetCode(true) // Written as-is
+			heading.AppendChild(heading, anchor)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -3,9 +3,12 @@ package main
 import (
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // loadLayouts discovers and parses layout templates from the source directory,
@@ -17,9 +20,17 @@ import (
 // _layout.{name}.html are variants that override blocks from the base.
 // They contain {{ define "blockname" }}...{{ end }} to replace base blocks.
 //
-// Returns a map: "" → base template, "name" → variant template.
-func loadLayouts(src string) (map[string]*template.Template, error) {
-	layouts := make(map[string]*template.Template)
+// Templates get the shared function library from funcs, and every partial
+// in partials.
+//
+// Returns a layoutSet: "" → base template, "name" → variant template.
+func loadLayouts(src string, funcs *templateFuncs, partials *partialSet) (*layoutSet, error) {
+	layouts := &layoutSet{
+		templates:  make(map[string]*template.Template),
+		digests:    make(map[string]string),
+		listsPages: make(map[string]bool),
+		builtin:    make(map[string]bool),
+	}
 
 	// Try to read base layout from source directory
 	basePath := filepath.Join(src, "_layout.html")
@@ -36,54 +47,61 @@ func loadLayouts(src string) (map[string]*template.Template, error) {
 		fmt.Printf("  Using built-in layout (create _layout.html to customize)\n")
 	}
 
-	funcMap := template.FuncMap{
-		"safeHTML": func(s string) template.HTML { return template.HTML(s) },
-		"linkIcon": func(name string) template.HTML { return template.HTML(linkIcon(name)) },
-		"navURL": func(basePath, url string) string {
-			if url == "" {
-				return ""
-			}
-			if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "mailto:") || strings.HasPrefix(url, "#") {
-				return url
-			}
-			basePath = strings.TrimRight(basePath, "/")
-			if strings.HasPrefix(url, "/") {
-				return basePath + url
-			}
-			if basePath == "" {
-				return "/" + url
-			}
-			return basePath + "/" + url
-		},
-		"formatDate": func(s string) string {
-			if t, ok := ParseDate(s); ok {
-				return t.Format("January 2, 2006")
-			}
-			return s
-		},
-	}
-
-	baseTmpl, err := template.New("layout").Funcs(funcMap).Parse(string(baseBytes))
+	baseTmpl, err := template.New("layout").Funcs(funcs.funcMap()).Parse(string(baseBytes))
 	if err != nil {
 		return nil, fmt.Errorf("parsing _layout.html: %w", err)
 	}
-	layouts[""] = baseTmpl
+	if err := partials.addTo(baseTmpl); err != nil {
+		return nil, err
+	}
+	layouts.add("", baseTmpl, baseBytes)
 
 	// Discover named variants from source directory
-	if err := discoverVariants(src, baseTmpl, layouts); err != nil {
+	if err := discoverVariants(src, baseTmpl, baseBytes, layouts); err != nil {
 		return nil, err
 	}
 
 	// Also load embedded variants that aren't overridden by source
-	if err := discoverEmbeddedVariants(baseTmpl, layouts); err != nil {
+	if err := discoverEmbeddedVariants(baseTmpl, baseBytes, layouts); err != nil {
 		return nil, err
 	}
 
+	// Every layout can call every partial
+	for name, d := range layouts.digests {
+		layouts.digests[name] = digest([]byte(d), []byte(partials.digest()))
+		layouts.listsPages[name] = layouts.listsPages[name] || partials.listsPages()
+	}
+
 	return layouts, nil
 }
 
+// layoutSet holds parsed layouts keyed by variant name ("" is the base),
+// plus a digest of the sources each one was parsed from for the build cache.
+type layoutSet struct {
+	templates  map[string]*template.Template
+	digests    map[string]string
+	listsPages map[string]bool // Variants whose sources read the full page list
+	builtin    map[string]bool // Variants embedded in moat rather than in the source directory
+}
+
+// add registers a variant parsed from sources (the base, then the variant).
+func (ls *layoutSet) add(name string, tmpl *template.Template, sources ...[]byte) {
+	ls.templates[name] = tmpl
+	ls.digests[name] = digest(sources...)
+	ls.listsPages[name] = false
+	for _, data := range sources {
+		ls.listsPages[name] = ls.listsPages[name] || listsPages(data)
+	}
+}
+
+// get returns the template for a variant name ("" for the base layout).
+func (ls *layoutSet) get(name string) (*template.Template, bool) {
+	tmpl, ok := ls.templates[name]
+	return tmpl, ok
+}
+
 // discoverVariants finds _layout.{name}.html files in the source directory.
-func discoverVariants(src string, baseTmpl *template.Template, layouts map[string]*template.Template) error {
+func discoverVariants(src string, baseTmpl *template.Template, baseBytes []byte, layouts *layoutSet) error {
 	entries, err := os.ReadDir(src)
 	if err != nil {
 		return fmt.Errorf("reading source directory: %w", err)
@@ -111,7 +129,7 @@ func discoverVariants(src string, baseTmpl *template.Template, layouts map[strin
 			return fmt.Errorf("parsing %s: %w", name, err)
 		}
 
-		layouts[variant] = cloned
+		layouts.add(variant, cloned, baseBytes, variantBytes)
 		fmt.Printf("  Layout: %s → %s\n", name, variant)
 	}
 
@@ -119,7 +137,7 @@ func discoverVariants(src string, baseTmpl *template.Template, layouts map[strin
 }
 
 // discoverEmbeddedVariants loads embedded layout variants not already in layouts.
-func discoverEmbeddedVariants(baseTmpl *template.Template, layouts map[string]*template.Template) error {
+func discoverEmbeddedVariants(baseTmpl *template.Template, baseBytes []byte, layouts *layoutSet) error {
 	entries, err := listEmbeddedDir("")
 	if err != nil {
 		return nil // No embedded files
@@ -135,7 +153,7 @@ func discoverEmbeddedVariants(baseTmpl *template.Template, layouts map[string]*t
 		variant = strings.TrimSuffix(variant, ".html")
 
 		// Skip if already loaded from source
-		if _, exists := layouts[variant]; exists {
+		if _, exists := layouts.get(variant); exists {
 			continue
 		}
 
@@ -152,9 +170,185 @@ func discoverEmbeddedVariants(baseTmpl *template.Template, layouts map[string]*t
 			continue
 		}
 
-		layouts[variant] = cloned
+		layouts.add(variant, cloned, baseBytes, variantBytes)
+		layouts.builtin[variant] = true
 		fmt.Printf("  Layout: %s → %s (built-in)\n", name, variant)
 	}
 
 	return nil
 }
+
+// partialSet holds partial templates keyed by template name
+// ("partials/footer"): the built-in defaults, overlaid by the site's
+// _partials/ directory file by file.
+type partialSet struct {
+	sources map[string][]byte
+	files   map[string]string // Template name → file, for error messages
+}
+
+// loadPartials reads built-in partials, then _partials/**/*.html from src.
+// _partials/blog/card.html becomes "partials/blog/card".
+func loadPartials(src string) (*partialSet, error) {
+	ps := &partialSet{sources: make(map[string][]byte), files: make(map[string]string)}
+
+	err := fs.WalkDir(embeddedFS, "embed/_partials", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".html") {
+			return err
+		}
+		data, err := embeddedFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "embed/_"), ".html")
+		ps.sources[name] = data
+		ps.files[name] = "built-in " + name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading built-in partials: %w", err)
+	}
+
+	dir := filepath.Join(src, "_partials")
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".html") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		inDir, _ := filepath.Rel(dir, path)
+		name := "partials/" + strings.TrimSuffix(filepath.ToSlash(inDir), ".html")
+		ps.sources[name] = data
+		ps.files[name] = rel
+		fmt.Printf("  Partial: %s\n", name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading _partials: %w", err)
+	}
+	return ps, nil
+}
+
+// addTo parses every partial into t's template set.
+func (ps *partialSet) addTo(t *template.Template) error {
+	if ps == nil {
+		return nil
+	}
+	for _, name := range ps.names() {
+		if _, err := t.New(name).Parse(string(ps.sources[name])); err != nil {
+			return fmt.Errorf("parsing %s: %w", ps.files[name], err)
+		}
+	}
+	return nil
+}
+
+// digest covers every partial, for the build cache.
+func (ps *partialSet) digest() string {
+	if ps == nil {
+		return ""
+	}
+	var parts [][]byte
+	for _, name := range ps.names() {
+		parts = append(parts, []byte(name), ps.sources[name])
+	}
+	return digest(parts...)
+}
+
+// listsPages reports whether any partial reads the full page list.
+func (ps *partialSet) listsPages() bool {
+	if ps == nil {
+		return false
+	}
+	for _, data := range ps.sources {
+		if listsPages(data) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *partialSet) names() []string {
+	names := make([]string, 0, len(ps.sources))
+	for name := range ps.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pageTemplates is a copy of every layout and shortcode template whose
+// markdownify renders through one page's renderer, so headings share the
+// page's IDs and relative links resolve from the page's directory.
+type pageTemplates struct {
+	funcs      *templateFuncs
+	layouts    map[string]*template.Template
+	shortcodes map[string]*template.Template
+}
+
+// templatePool hands out pageTemplates to concurrent renders. A template's
+// functions can't be swapped once it has run, so each render borrows a
+// copy and points it at its page; the originals are only ever cloned.
+type templatePool struct {
+	funcs      *templateFuncs
+	layouts    *layoutSet
+	shortcodes *shortcodeRegistry
+
+	mu   sync.Mutex
+	free []*pageTemplates
+}
+
+func newTemplatePool(funcs *templateFuncs, layouts *layoutSet, shortcodes *shortcodeRegistry) *templatePool {
+	return &templatePool{funcs: funcs, layouts: layouts, shortcodes: shortcodes}
+}
+
+// get returns templates whose markdownify renders with md. Return them
+// with put once the page is written.
+func (p *templatePool) get(md markdownRenderer) (*pageTemplates, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.free); n > 0 {
+		t := p.free[n-1]
+		p.free = p.free[:n-1]
+		t.funcs.md = md
+		return t, nil
+	}
+
+	funcs := *p.funcs
+	funcs.md = md
+	t := &pageTemplates{
+		funcs:      &funcs,
+		layouts:    make(map[string]*template.Template, len(p.layouts.templates)),
+		shortcodes: make(map[string]*template.Template, len(p.shortcodes.templates)),
+	}
+	bound := template.FuncMap{"markdownify": t.funcs.markdownify}
+	for name, tmpl := range p.layouts.templates {
+		c, err := tmpl.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("cloning layout %q: %w", name, err)
+		}
+		t.layouts[name] = c.Funcs(bound)
+	}
+	for name, tmpl := range p.shortcodes.templates {
+		c, err := tmpl.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("cloning shortcode %s: %w", name, err)
+		}
+		t.shortcodes[name] = c.Funcs(bound)
+	}
+	return t, nil
+}
+
+func (p *templatePool) put(t *pageTemplates) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t.funcs.md = nil
+	p.free = append(p.free, t)
+}
@@ -3,6 +3,8 @@
 // Usage:
 //
 //	moat build <src> <dst>    Build static site from markdown source
+//	moat dev <src> [--port]   Watch source, rebuild, and live-reload
+//	moat check <src>          Validate links, anchors, and wikilinks
 //	moat serve <dir> [--port] Serve static files for local preview
 package main
 
@@ -10,6 +12,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 var version = "dev"
@@ -23,55 +26,16 @@ func main() {
 	switch os.Args[1] {
 	case "build":
 		if len(os.Args) < 4 {
-			fmt.Fprintf(os.Stderr, "Usage: moat build <src> <dst> [--config PATH] [--site-name NAME] [--base-path PATH]\n")
+			fmt.Fprintf(os.Stderr, "Usage: moat build <src> <dst> [--config PATH] [--site-name NAME] [--base-path PATH] [--no-cache] [--jobs N] [--strict] [--drafts] [--future] [--expired]\n")
 			os.Exit(1)
 		}
 		src := os.Args[2]
 		dst := os.Args[3]
-		siteName := ""
-		basePath := ""
-		configPath := ""
-		hasSiteName := false
-		hasBasePath := false
-		for i, arg := range os.Args {
-			if arg == "--site-name" && i+1 < len(os.Args) {
-				siteName = os.Args[i+1]
-				hasSiteName = true
-			}
-			if arg == "--base-path" && i+1 < len(os.Args) {
-				basePath = os.Args[i+1]
-				hasBasePath = true
-			}
-			if arg == "--config" && i+1 < len(os.Args) {
-				configPath = os.Args[i+1]
-			}
-		}
-
-		// Auto-detect config.toml in src directory if --config not given
-		if configPath == "" {
-			candidate := filepath.Join(src, "config.toml")
-			if _, err := os.Stat(candidate); err == nil {
-				configPath = candidate
-			}
-		}
-
-		// Load config
-		cfg, err := LoadConfig(configPath)
+		cfg, err := loadBuildConfig(src, os.Args)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
-
-		// Config file provides defaults; CLI flags override
-		if !hasSiteName && cfg.SiteName != "" {
-			siteName = cfg.SiteName
-		}
-		if !hasBasePath && cfg.BasePath != "" {
-			basePath = cfg.BasePath
-		}
-
-		cfg.SiteName = siteName
-		cfg.BasePath = basePath
 		if err := Build(src, dst, cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
@@ -84,16 +48,51 @@ func main() {
 		}
 		dir := os.Args[2]
 		port := "8080"
-		for i, arg := range os.Args {
-			if arg == "--port" && i+1 < len(os.Args) {
-				port = os.Args[i+1]
-			}
+		if v, ok := flagValue(os.Args, "--port"); ok {
+			port = v
 		}
 		if err := Serve(dir, port); err != nil {
 			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
 
+	case "dev":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Usage: moat dev <src> [--port PORT] [--config PATH] [--site-name NAME] [--base-path PATH]\n")
+			os.Exit(1)
+		}
+		src := os.Args[2]
+		port := "8080"
+		if v, ok := flagValue(os.Args, "--port"); ok {
+			port = v
+		}
+		if err := Dev(src, port, os.Args); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "check":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Usage: moat check <src> [--config PATH] [--site-name NAME] [--base-path PATH]\n")
+			os.Exit(1)
+		}
+		src := os.Args[2]
+		cfg, err := loadBuildConfig(src, os.Args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		issues, err := Check(src, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(issues) > 0 {
+			printIssues(issues)
+			os.Exit(1)
+		}
+		fmt.Printf("No problems found\n")
+
 	case "init":
 		dir := "docs"
 		if len(os.Args) >= 3 {
@@ -122,7 +121,89 @@ Usage:
     --config PATH      Config file (default: <src>/config.toml)
     --site-name NAME   Site name for templates (default: "Site")
     --base-path PATH   URL prefix for GitHub project pages (e.g. /moat)
+    --no-cache         Render every page and skip the build manifest
+    --jobs N           Pages to render in parallel (default: GOMAXPROCS)
+    --strict           Fail the build on broken links, anchors, or wikilinks
+    --drafts           Also build pages with draft: true
+    --future           Also build pages whose publish_date is still to come
+    --expired          Also build pages past their expiry_date
+  moat dev <src> [flags]                      Watch, rebuild, and live-reload in the browser
+    --port PORT        Port to listen on (default: 8080)
+    (also accepts the build flags above)
+  moat check <src> [flags]                    Report broken links, anchors, and wikilinks
+    (accepts --config, --site-name, --base-path, --drafts, --future, --expired)
   moat serve <dir> [--port PORT]              Serve for local preview
   moat version                                Print version
 `, version)
 }
+
+// loadBuildConfig loads config for src and applies CLI flag overrides.
+// config.toml in src is used when --config is not given.
+func loadBuildConfig(src string, args []string) (Config, error) {
+	configPath, _ := flagValue(args, "--config")
+
+	// Auto-detect config.toml in src directory if --config not given
+	if configPath == "" {
+		candidate := filepath.Join(src, "config.toml")
+		if _, err := os.Stat(candidate); err == nil {
+			configPath = candidate
+		}
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return cfg, err
+	}
+
+	// Config file provides defaults; CLI flags override
+	if v, ok := flagValue(args, "--site-name"); ok {
+		cfg.SiteName = v
+	}
+	if v, ok := flagValue(args, "--base-path"); ok {
+		cfg.BasePath = v
+	}
+	if v, ok := flagValue(args, "--jobs"); ok {
+		jobs, err := strconv.Atoi(v)
+		if err != nil || jobs < 1 {
+			return cfg, fmt.Errorf("invalid --jobs value %q (expected a positive integer)", v)
+		}
+		cfg.Jobs = jobs
+	}
+	if hasFlag(args, "--no-cache") {
+		disabled := false
+		cfg.Cache = &disabled
+	}
+	if hasFlag(args, "--strict") {
+		cfg.Strict = true
+	}
+	if hasFlag(args, "--drafts") {
+		cfg.Drafts = true
+	}
+	if hasFlag(args, "--future") {
+		cfg.Future = true
+	}
+	if hasFlag(args, "--expired") {
+		cfg.Expired = true
+	}
+	return cfg, nil
+}
+
+// hasFlag reports whether a boolean flag appears in args.
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// flagValue returns the value following name in args, if present.
+func flagValue(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if arg == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -3,14 +3,23 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"go.abhg.dev/goldmark/wikilink"
 )
 
@@ -35,6 +44,12 @@ func newMarkdown(resolver wikilink.Resolver) goldmark.Markdown {
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+			parser.WithASTTransformers(
+				util.Prioritized(linkTransformer{}, 100),
+				util.Prioritized(tocTransformer{}, 75), // Before anchors are added to headings
+				util.Prioritized(headingAnchorTransformer{}, 50),
+			),
 		),
 		goldmark.WithRendererOptions(
 			html.WithUnsafe(),
@@ -54,9 +69,51 @@ func RenderMarkdownWithResolver(source []byte, resolver wikilink.Resolver) ([]by
 	return renderMarkdownWith(newMarkdown(resolver), source)
 }
 
+// markdownPool reuses goldmark pipelines across renders. Each render takes
+// an instance no other goroutine is using, so pools are safe to share
+// between concurrent page renders.
+type markdownPool struct {
+	pool sync.Pool
+}
+
+func newMarkdownPool(resolver wikilink.Resolver) *markdownPool {
+	p := &markdownPool{}
+	p.pool.New = func() any { return newMarkdown(resolver) }
+	return p
+}
+
+// render converts source with a pooled pipeline. pr, if set, is made
+// available to AST transformers through the parser context, and its
+// heading ID registry is used so IDs are unique across the page.
+func (p *markdownPool) render(source []byte, pr *pageRenderer) ([]byte, error) {
+	md := p.pool.Get().(goldmark.Markdown)
+	defer p.pool.Put(md)
+
+	ids := newHeadingIDs()
+	if pr != nil {
+		ids = pr.ids
+	}
+	ctx := parser.NewContext(parser.WithIDs(ids))
+	if pr != nil {
+		ctx.Set(pageRendererKey, pr)
+	}
+	var buf bytes.Buffer
+	if err := md.Convert(source, &buf, parser.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	if pr != nil {
+		for _, id := range ids.clash {
+			fmt.Fprintf(pr.log, "  Warning: %s has more than one heading with id %q\n", pr.relPath, id)
+		}
+		ids.clash = nil
+	}
+	return buf.Bytes(), nil
+}
+
 func renderMarkdownWith(md goldmark.Markdown, source []byte) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := md.Convert(source, &buf); err != nil {
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	if err := md.Convert(source, &buf, parser.WithContext(ctx)); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -64,8 +121,15 @@ func renderMarkdownWith(md goldmark.Markdown, source []byte) ([]byte, error) {
 
 // pageResolver resolves [[wiki links]] to page URLs using a title→URL map.
 // Matching is case-insensitive and ignores leading/trailing whitespace.
+// It also maps source paths to URLs so markdown links to .md files can be
+// rewritten to the final page URL.
 type pageResolver struct {
-	pages map[string]string // lowercase title → URL path
+	pages    map[string]string // lowercase title → URL path
+	paths    map[string]string // slash-separated source path → URL path
+	basePath string
+	md       *markdownPool // Pipelines bound to this resolver
+
+	headingAnchors bool // Append permalink anchors to headings
 }
 
 // newPageResolver builds a resolver from a list of pages.
@@ -73,9 +137,11 @@ type pageResolver struct {
 // and a warning is printed.
 func newPageResolver(pages []Page, basePath string) *pageResolver {
 	m := make(map[string]string, len(pages))
+	paths := make(map[string]string, len(pages))
 	for _, p := range pages {
 		title := pageTitle(p)
 		url := basePath + pageURL(p)
+		paths[filepath.ToSlash(p.RelPath)] = url
 		key := strings.ToLower(title)
 		if existing, ok := m[key]; ok {
 			fmt.Printf("  Warning: duplicate wiki link target %q (%s shadows %s)\n", title, existing, url)
@@ -83,7 +149,124 @@ func newPageResolver(pages []Page, basePath string) *pageResolver {
 		}
 		m[key] = url
 	}
-	return &pageResolver{pages: m}
+	r := &pageResolver{pages: m, paths: paths, basePath: basePath}
+	r.md = newMarkdownPool(r)
+	return r
+}
+
+// render converts markdown to HTML with this resolver, reusing pooled
+// goldmark pipelines instead of building one per call. Links are resolved
+// as if from a page at the site root.
+func (r *pageResolver) render(source []byte) ([]byte, error) {
+	return r.md.render(source, r.forPage("", io.Discard))
+}
+
+// forPage returns a renderer for the page at relPath. Link warnings are
+// written to log.
+func (r *pageResolver) forPage(relPath string, log io.Writer) *pageRenderer {
+	return &pageRenderer{resolver: r, relPath: filepath.ToSlash(relPath), log: log, ids: newHeadingIDs()}
+}
+
+// markdownRenderer converts markdown to HTML. Implemented by *pageResolver
+// (site-wide) and *pageRenderer (bound to one page).
+type markdownRenderer interface {
+	render(source []byte) ([]byte, error)
+}
+
+// pageRenderer renders markdown on behalf of one page, so relative links
+// resolve against that page's source file and warnings name it.
+type pageRenderer struct {
+	resolver   *pageResolver
+	relPath    string // Slash-separated source path, e.g. "01-guide/02-config.md"
+	log        io.Writer
+	unresolved []string    // [[wikilink]] targets that matched no page
+	ids        *headingIDs // Heading IDs used so far on this page
+	headings   []TOCItem   // Headings rendered so far, for the table of contents
+}
+
+func (pr *pageRenderer) render(source []byte) ([]byte, error) {
+	return pr.resolver.md.render(source, pr)
+}
+
+var pageRendererKey = parser.NewContextKey()
+
+// linkTransformer rewrites link and image destinations after parsing:
+// links to .md source files become their page URL, and root-relative
+// destinations get base_path, matching how topnav links are treated.
+// It also records wikilinks that match no page, for moat check.
+type linkTransformer struct{}
+
+func (linkTransformer) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	pr, _ := pc.Get(pageRendererKey).(*pageRenderer)
+	if pr == nil {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = []byte(pr.rewriteLink(string(n.Destination), true))
+		case *ast.Image:
+			n.Destination = []byte(pr.rewriteLink(string(n.Destination), false))
+		case *wikilink.Node:
+			target := strings.TrimSpace(string(n.Target))
+			if _, ok := pr.resolver.pages[strings.ToLower(target)]; target != "" && !ok {
+				pr.unresolved = append(pr.unresolved, target)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// reURLScheme matches a URL scheme such as "https:" or "mailto:".
+var reURLScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// rewriteLink maps a markdown destination to its final form. When pages is
+// set, destinations naming a .md file are resolved (relative to the current
+// page, or to the source root when they start with "/") to the page URL,
+// keeping any query or fragment. Unresolvable .md targets are left as-is
+// with a warning.
+func (pr *pageRenderer) rewriteLink(dest string, pages bool) string {
+	if dest == "" || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "//") || reURLScheme.MatchString(dest) {
+		return dest
+	}
+
+	target, suffix := dest, ""
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		target, suffix = dest[:i], dest[i:]
+	}
+
+	if pages && strings.HasSuffix(target, ".md") {
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(path.Dir(pr.relPath), target)
+		}
+		if u, ok := pr.resolver.paths[path.Clean(target)]; ok {
+			return u + suffix
+		}
+		fmt.Fprintf(pr.log, "  Warning: %s links to %s, which is not a page\n", pr.relPath, dest)
+		return dest
+	}
+
+	if strings.HasPrefix(dest, "/") {
+		return pr.resolver.withBasePath(dest)
+	}
+	return dest
+}
+
+// withBasePath prefixes a root-relative URL with base_path unless it
+// already carries it.
+func (r *pageResolver) withBasePath(u string) string {
+	if r.basePath == "" || u == r.basePath || strings.HasPrefix(u, r.basePath+"/") {
+		return u
+	}
+	return r.basePath + u
 }
 
 func (r *pageResolver) ResolveWikilink(n *wikilink.Node) ([]byte, error) {
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/yuin/goldmark/ast"
 )
 
 func TestRenderMarkdownBasic(t *testing.T) {
@@ -100,3 +103,126 @@ func TestRenderMarkdownWikilinkWithBasePath(t *testing.T) {
 		t.Errorf("expected basePath in link, got: %s", out)
 	}
 }
+
+func TestRenderRewritesMarkdownLinksToPageURLs(t *testing.T) {
+	pages := []Page{
+		{RelPath: "01-guide/01-intro.md"},
+		{RelPath: "01-guide/02-config.md"},
+		{RelPath: "02-reference/01-cli.md", Frontmatter: Frontmatter{URL: "commands"}},
+	}
+	resolver := newPageResolver(pages, "/moat")
+	var log strings.Builder
+	pr := resolver.forPage("01-guide/01-intro.md", &log)
+
+	source := strings.Join([]string{
+		"[sibling](02-config.md)",
+		"[parent](../02-reference/01-cli.md#flags)",
+		"[rooted](/01-guide/02-config.md)",
+		"[missing](nope.md)",
+		"[abs](/guide/config/)",
+		"[already](/moat/guide/config/)",
+		"[external](https://example.com/a.md)",
+		"![img](/_static/logo.svg)",
+	}, "\n\n")
+	out, err := pr.render([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+
+	for _, want := range []string{
+		`href="/moat/guide/config/">sibling`,
+		`href="/moat/commands/#flags">parent`,
+		`href="/moat/guide/config/">rooted`,
+		`href="nope.md">missing`,
+		`href="/moat/guide/config/">abs`,
+		`href="/moat/guide/config/">already`,
+		`href="https://example.com/a.md">external`,
+		`src="/moat/_static/logo.svg"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %s in output, got: %s", want, html)
+		}
+	}
+
+	if !strings.Contains(log.String(), "01-guide/01-intro.md links to nope.md") {
+		t.Errorf("expected warning for unresolvable .md link, got: %q", log.String())
+	}
+}
+
+func TestHeadingIDsUniqueAcrossPageRenders(t *testing.T) {
+	resolver := newPageResolver(nil, "")
+	pr := resolver.forPage("page.md", io.Discard)
+
+	// Shortcode inner content and the body render separately but share IDs
+	inner, err := pr.render([]byte("## Example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := pr.render([]byte("## Example\n\n## Custom {#setup}\n\n## Setup\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(inner), `<h2 id="example">`) {
+		t.Errorf("inner: %s", inner)
+	}
+	for _, want := range []string{`<h2 id="example-1">Example</h2>`, `<h2 id="setup">Custom</h2>`, `<h2 id="setup-1">Setup</h2>`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in body, got: %s", want, body)
+		}
+	}
+}
+
+func TestHeadingIDsReportExplicitClash(t *testing.T) {
+	var log strings.Builder
+	pr := newPageResolver(nil, "").forPage("page.md", &log)
+	if _, err := pr.render([]byte("## Install\n\n## Setup {#install}\n\n## Other {#other}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if want := `page.md has more than one heading with id "install"`; !strings.Contains(log.String(), want) {
+		t.Errorf("expected warning %q, got: %q", want, log.String())
+	}
+	if strings.Contains(log.String(), `"other"`) {
+		t.Errorf("unexpected warning for a unique id: %q", log.String())
+	}
+}
+
+func TestHeadingIDsUnicodeSlugs(t *testing.T) {
+	ids := newHeadingIDs()
+	tests := []struct{ in, want string }{
+		{"Über uns", "über-uns"},
+		{"日本語のガイド", "日本語のガイド"},
+		{"Hello, World!", "hello-world"},
+		{"  snake_case -- and  spaces ", "snake-case-and-spaces"},
+		{"See [the docs](https://example.com/x)", "see-the-docs"},
+		{"!!!", "heading"},
+		{"Hello, World!", "hello-world-1"},
+	}
+	for _, tt := range tests {
+		if got := string(ids.Generate([]byte(tt.in), ast.KindHeading)); got != tt.want {
+			t.Errorf("Generate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	resolver := newPageResolver(nil, "")
+	out, err := resolver.render([]byte("## Install\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "heading-anchor") {
+		t.Errorf("anchors should be off by default: %s", out)
+	}
+
+	resolver.headingAnchors = true
+	out, err = resolver.render([]byte("## Install\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<h2 id="install">Install<a class="heading-anchor" href="#install" aria-label="Permalink to this section"></a></h2>`
+	if !strings.Contains(string(out), want) {
+		t.Errorf("expected %s, got: %s", want, out)
+	}
+}
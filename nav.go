@@ -3,134 +3,372 @@ package main
 import (
 	"fmt"
 	"html"
+	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // NavItem represents a page or section in the navigation.
 type NavItem struct {
-	Title    string
-	Path     string // URL path relative to site root, e.g. "/guide/agents"
-	Children []NavItem
+	Title     string
+	Path      string // URL path relative to site root, e.g. "/guide/agents"; for sections, the index.md URL if any
+	Children  []NavItem
+	External  bool // Path is an absolute URL, rendered without base_path
+	Separator bool // A divider between groups; other fields are empty
+	Active    bool // The page being rendered (set per page, see markNav)
+	Ancestor  bool // A section containing the page being rendered
 }
 
-// BuildNav constructs a navigation tree from a list of pages.
-// Pages are grouped by directory, sorted alphabetically within each group.
-// Number prefixes (01-, 02-) control ordering but are stripped from display.
-func BuildNav(pages []Page) []NavItem {
-	// Group by directory
-	rootPages := []Page{}
-	sections := map[string][]Page{}
-	sectionIndex := map[string]Page{} // section dir → index.md page
-	sectionOrder := []string{}
+// NavEntry is one item of an explicit nav from _nav.yaml or [[nav]] config.
+// Exactly one of Page, URL, Separator, or a Title with Children is expected.
+type NavEntry struct {
+	Title     string     `toml:"title" yaml:"title"`
+	Page      string     `toml:"page" yaml:"page"` // Source path ("01-guide/01-intro.md") or wikilink ("[[Intro]]")
+	URL       string     `toml:"url" yaml:"url"`   // External or root-relative link
+	Separator bool       `toml:"separator" yaml:"separator"`
+	Children  []NavEntry `toml:"children" yaml:"children"`
+}
+
+const navFilename = "_nav.yaml"
+
+// loadNavFile reads the explicit nav from src/_nav.yaml, returning nil if
+// the file doesn't exist.
+func loadNavFile(src string) ([]NavEntry, error) {
+	data, err := os.ReadFile(filepath.Join(src, navFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", navFilename, err)
+	}
+	var entries []NavEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", navFilename, err)
+	}
+	if entries == nil {
+		entries = []NavEntry{} // An empty file still means "explicit nav"
+	}
+	return entries, nil
+}
+
+// BuildExplicitNav builds the navigation tree from curated entries instead
+// of the directory structure. Every page reference must resolve; all bad
+// entries are reported together. It also returns the pages no entry
+// references (excluding the root index and nav_hidden pages), sorted.
+func BuildExplicitNav(entries []NavEntry, pages []Page) ([]NavItem, []string, error) {
+	byPath := make(map[string]Page, len(pages))
+	byTitle := make(map[string]Page, len(pages))
+	for _, p := range pages {
+		byPath[filepath.ToSlash(p.RelPath)] = p
+		key := strings.ToLower(pageTitle(p))
+		if _, ok := byTitle[key]; !ok {
+			byTitle[key] = p
+		}
+	}
+
+	used := make(map[string]bool)
+	var problems []string
+	var build func(entries []NavEntry, where string) []NavItem
+	build = func(entries []NavEntry, where string) []NavItem {
+		items := []NavItem{}
+		for i, e := range entries {
+			at := fmt.Sprintf("%s[%d]", where, i)
+			kinds := 0
+			for _, set := range []bool{e.Page != "", e.URL != "", e.Separator} {
+				if set {
+					kinds++
+				}
+			}
+			if kinds > 1 {
+				problems = append(problems, fmt.Sprintf("%s: set only one of page, url, or separator", at))
+				continue
+			}
+
+			switch {
+			case e.Separator:
+				items = append(items, NavItem{Separator: true})
+				continue
+			case e.Page != "":
+				var p Page
+				var ok bool
+				if title, isLink := strings.CutPrefix(e.Page, "[["); isLink {
+					p, ok = byTitle[strings.ToLower(strings.TrimSuffix(title, "]]"))]
+				} else {
+					p, ok = byPath[strings.TrimPrefix(path.Clean(e.Page), "/")]
+				}
+				if !ok {
+					problems = append(problems, fmt.Sprintf("%s: page %q not found", at, e.Page))
+					continue
+				}
+				used[p.RelPath] = true
+				item := NavItem{Title: navTitle(p), Path: pageURL(p)}
+				if e.Title != "" {
+					item.Title = e.Title
+				}
+				item.Children = build(e.Children, at)
+				items = append(items, item)
+			case e.URL != "":
+				if e.Title == "" {
+					problems = append(problems, fmt.Sprintf("%s: url %q needs a title", at, e.URL))
+					continue
+				}
+				items = append(items, NavItem{
+					Title:    e.Title,
+					Path:     e.URL,
+					External: !strings.HasPrefix(e.URL, "/"),
+					Children: build(e.Children, at),
+				})
+			case e.Title != "" && len(e.Children) > 0:
+				items = append(items, NavItem{Title: e.Title, Children: build(e.Children, at)})
+			default:
+				problems = append(problems, fmt.Sprintf("%s: expected page, url, separator, or title with children", at))
+			}
+		}
+		return items
+	}
+	nav := build(entries, "nav")
+
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("invalid nav:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	var orphans []string
+	for _, p := range pages {
+		if p.RelPath != "index.md" && !p.Frontmatter.NavHidden && !used[p.RelPath] {
+			orphans = append(orphans, p.RelPath)
+		}
+	}
+	sort.Strings(orphans)
+	return nav, orphans, nil
+}
 
+// navDir is a source directory while the nav tree is being assembled.
+type navDir struct {
+	name  string // Directory name as on disk, e.g. "01-guide"
+	index *Page  // index.md in this directory, if any
+	pages []Page
+	dirs  map[string]*navDir
+}
+
+func newNavDir(name string) *navDir {
+	return &navDir{name: name, dirs: map[string]*navDir{}}
+}
+
+// BuildNav constructs a navigation tree from a list of pages, one level per
+// directory. Root pages come first, then sections. Within a section, pages
+// and subsections sort by name, except that dated pages come first, newest
+// first. Number prefixes (01-, 02-) control ordering but are stripped from
+// display. A section's index.md becomes its landing page.
+//
+// Frontmatter adjusts this: weight moves an item ahead of unweighted ones
+// (lowest first), nav_title replaces the label, nav_hidden leaves a page (or,
+// on index.md, a whole section) out, and nav_order on an index.md picks the
+// order for that section.
+func BuildNav(pages []Page) []NavItem {
+	root := newNavDir("")
 	for _, p := range pages {
 		if p.RelPath == "index.md" {
+			root.index = &p
 			continue // Skip root index from nav listing
 		}
 
+		d := root
 		dir := filepath.Dir(p.RelPath)
-		if dir == "." {
-			rootPages = append(rootPages, p)
-		} else {
-			// Use top-level directory only (max 2 levels)
-			parts := strings.SplitN(dir, string(filepath.Separator), 2)
-			section := parts[0]
-			// Track section index pages for nav_children support
-			if filepath.Base(p.RelPath) == "index.md" && len(parts) == 1 {
-				sectionIndex[section] = p
-				if _, exists := sections[section]; !exists {
-					sectionOrder = append(sectionOrder, section)
-					sections[section] = []Page{} // init so we don't add to order again
+		if dir != "." {
+			for _, part := range strings.Split(dir, string(filepath.Separator)) {
+				sub, ok := d.dirs[part]
+				if !ok {
+					sub = newNavDir(part)
+					d.dirs[part] = sub
 				}
-				continue // Don't add index.md as a child page
+				d = sub
 			}
-			if _, exists := sections[section]; !exists {
-				sectionOrder = append(sectionOrder, section)
-			}
-			sections[section] = append(sections[section], p)
 		}
+
+		if filepath.Base(p.RelPath) == "index.md" {
+			d.index = &p
+			continue // Don't add index.md as a child page
+		}
+		d.pages = append(d.pages, p)
 	}
 
-	sort.Strings(sectionOrder)
+	// Root-level pages first, then sections, each ordered on their own.
+	// Dates only reorder pages inside sections.
+	order := ""
+	if root.index != nil {
+		order = root.index.Frontmatter.NavOrder
+	}
+	var rootPages, rootSections []navEntry
+	for _, p := range root.pages {
+		if !p.Frontmatter.NavHidden {
+			rootPages = append(rootPages, pageNavEntry(p))
+		}
+	}
+	for _, sub := range root.dirs {
+		if e, ok := sectionNavEntry(sub); ok {
+			rootSections = append(rootSections, e)
+		}
+	}
+	if order == "" {
+		order = navOrderName
+	}
+	sortNavEntries(rootPages, order)
+	sortNavEntries(rootSections, order)
 
 	var nav []NavItem
+	for _, e := range append(rootPages, rootSections...) {
+		nav = append(nav, e.item)
+	}
+	return nav
+}
 
-	// Root-level pages first (sorted)
-	sort.Slice(rootPages, func(i, j int) bool {
-		return rootPages[i].RelPath < rootPages[j].RelPath
-	})
-	for _, p := range rootPages {
-		nav = append(nav, NavItem{
-			Title: pageTitle(p),
-			Path:  pageURL(p),
-		})
-	}
-
-	// Then sections
-	for _, section := range sectionOrder {
-		pages := sections[section]
-
-		// If any page has a date, sort reverse-chronologically (newest first).
-		// Otherwise, sort alphabetically by path.
-		hasDate := false
-		for _, p := range pages {
-			if p.Frontmatter.Date != "" {
-				hasDate = true
-				break
-			}
+// navEntry is a nav item with the keys it can be sorted by.
+type navEntry struct {
+	name   string // File or directory name
+	title  string
+	date   time.Time // Parsed frontmatter date, zero for undated pages and sections
+	weight int
+	item   NavItem
+}
+
+func pageNavEntry(p Page) navEntry {
+	return navEntry{
+		name:   filepath.Base(p.RelPath),
+		title:  navTitle(p),
+		date:   p.DateTime,
+		weight: p.Frontmatter.Weight,
+		item:   NavItem{Title: navTitle(p), Path: pageURL(p)},
+	}
+}
+
+// sectionNavEntry builds the entry for a directory, reporting false if its
+// index.md hides it from the nav.
+func sectionNavEntry(d *navDir) (navEntry, bool) {
+	e := navEntry{name: d.name}
+	if d.index != nil {
+		if d.index.Frontmatter.NavHidden {
+			return e, false
 		}
-		if hasDate {
-			sort.Slice(pages, func(i, j int) bool {
-				di, dj := pages[i].Frontmatter.Date, pages[j].Frontmatter.Date
-				if di != dj {
-					return di > dj // reverse chronological
-				}
-				return pages[i].RelPath < pages[j].RelPath
-			})
-		} else {
-			sort.Slice(pages, func(i, j int) bool {
-				return pages[i].RelPath < pages[j].RelPath
-			})
-		}
-
-		children := []NavItem{}
-		for _, p := range pages {
-			children = append(children, NavItem{
-				Title: pageTitle(p),
-				Path:  pageURL(p),
-			})
-		}
-
-		// If section has an index.md with nav_children: false, show as
-		// a single link to the index instead of expanding children.
-		if idx, ok := sectionIndex[section]; ok {
-			if nc, exists := idx.Frontmatter.Extra["nav_children"]; exists && nc == false {
-				title := pageTitle(idx)
-				if title == "Index" || title == "" {
-					title = TitleFromDir(section)
-				}
-				nav = append(nav, NavItem{
-					Title: title,
-					Path:  pageURL(idx),
-				})
-				continue
+		e.weight = d.index.Frontmatter.Weight
+	}
+	e.item = buildNavSection(d)
+	e.title = e.item.Title
+	return e, true
+}
+
+// buildNavSection builds the nav item for a directory and everything below it.
+func buildNavSection(d *navDir) NavItem {
+	item := NavItem{Title: TitleFromDir(d.name)}
+	order := ""
+	if d.index != nil {
+		item.Path = pageURL(*d.index)
+		order = d.index.Frontmatter.NavOrder
+		if d.index.Frontmatter.NavTitle != "" {
+			item.Title = d.index.Frontmatter.NavTitle
+		}
+
+		// nav_children: false shows the section as a single link to its
+		// index instead of expanding children.
+		if nc, exists := d.index.Frontmatter.Extra["nav_children"]; exists && nc == false {
+			if title := navTitle(*d.index); d.index.Frontmatter.NavTitle == "" && title != "Index" && title != "" {
+				item.Title = title
 			}
+			return item
 		}
+	}
 
-		nav = append(nav, NavItem{
-			Title:    TitleFromDir(section),
-			Children: children,
-		})
+	var entries []navEntry
+	for _, p := range d.pages {
+		if !p.Frontmatter.NavHidden {
+			entries = append(entries, pageNavEntry(p))
+		}
 	}
+	for _, sub := range d.dirs {
+		if e, ok := sectionNavEntry(sub); ok {
+			entries = append(entries, e)
+		}
+	}
+	sortNavEntries(entries, order)
 
-	return nav
+	item.Children = []NavItem{}
+	for _, e := range entries {
+		item.Children = append(item.Children, e.item)
+	}
+	return item
+}
+
+// Values for frontmatter nav_order. The empty default combines weight, date,
+// and name; navOrderName is the same without dates, used at the root.
+const (
+	navOrderDate   = "date"
+	navOrderTitle  = "title"
+	navOrderWeight = "weight"
+	navOrderName   = "name"
+)
+
+// validNavOrder reports whether s is an accepted nav_order value.
+func validNavOrder(s string) bool {
+	return s == "" || s == navOrderDate || s == navOrderTitle || s == navOrderWeight
+}
+
+// sortNavEntries orders entries for a section with the given nav_order.
+// Every order falls back to the file name so results are stable.
+func sortNavEntries(entries []navEntry, order string) {
+	byWeight := func(a, b navEntry) (less, decided bool) {
+		if a.weight == b.weight {
+			return false, false
+		}
+		if a.weight == 0 || b.weight == 0 {
+			return a.weight != 0, true // Weighted before unweighted
+		}
+		return a.weight < b.weight, true
+	}
+	byDate := func(a, b navEntry) (less, decided bool) {
+		if a.date.Equal(b.date) {
+			return false, false
+		}
+		if a.date.IsZero() || b.date.IsZero() {
+			return !a.date.IsZero(), true // Undated last
+		}
+		return a.date.After(b.date), true // Newest first
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch order {
+		case navOrderDate:
+			if less, ok := byDate(a, b); ok {
+				return less
+			}
+		case navOrderTitle:
+			if ta, tb := strings.ToLower(a.title), strings.ToLower(b.title); ta != tb {
+				return ta < tb
+			}
+		case navOrderWeight, navOrderName:
+			if less, ok := byWeight(a, b); ok {
+				return less
+			}
+		default:
+			if less, ok := byWeight(a, b); ok {
+				return less
+			}
+			if less, ok := byDate(a, b); ok {
+				return less
+			}
+		}
+		return a.name < b.name
+	})
 }
 
 // RenderNav generates HTML for the navigation sidebar.
 // Uses oat's sidebar nav patterns: <ul> lists, <details> for sections, aria-current for active.
+// Sections nest to any depth; only those on the path to the current page start open.
 // basePath is prepended to all href values (e.g. "/moat" for GitHub project pages).
 // links are extra items rendered at the top of the nav (e.g. GitHub link).
 func RenderNav(items []NavItem, currentPath, basePath string, links []LinkConfig) string {
@@ -144,35 +382,121 @@ func RenderNav(items []NavItem, currentPath, basePath string, links []LinkConfig
 			html.EscapeString(link.URL), icon, html.EscapeString(link.Title)))
 	}
 
+	renderNavItems(&b, items, currentPath, basePath, "  ")
+
+	b.WriteString("</ul>\n</nav>\n")
+	return b.String()
+}
+
+func renderNavItems(b *strings.Builder, items []NavItem, currentPath, basePath, indent string) {
 	for _, item := range items {
-		if len(item.Children) > 0 {
-			// Section with children
-			b.WriteString(fmt.Sprintf("  <li>\n    <details open>\n      <summary>%s</summary>\n      <ul>\n",
-				html.EscapeString(item.Title)))
-			for _, child := range item.Children {
-				aria := ""
-				href := basePath + child.Path
-				if href == currentPath {
-					aria = ` aria-current="page"`
-				}
-				b.WriteString(fmt.Sprintf("        <li><a href=\"%s\"%s>%s</a></li>\n",
-					html.EscapeString(href), aria, html.EscapeString(child.Title)))
+		if item.Separator {
+			b.WriteString(fmt.Sprintf("%s<li role=\"separator\"><hr></li>\n", indent))
+			continue
+		}
+		if len(item.Children) == 0 {
+			// Page, or a section collapsed to its index
+			b.WriteString(fmt.Sprintf("%s<li>%s</li>\n", indent, navLink(item, currentPath, basePath)))
+			continue
+		}
+
+		open := ""
+		if navContains(item, currentPath, basePath) {
+			open = " open"
+		}
+		title := html.EscapeString(item.Title)
+		if item.Path != "" {
+			title = navLink(item, currentPath, basePath)
+		}
+		b.WriteString(fmt.Sprintf("%s<li>\n%s  <details%s>\n%s    <summary>%s</summary>\n%s    <ul>\n",
+			indent, indent, open, indent, title, indent))
+		renderNavItems(b, item.Children, currentPath, basePath, indent+"      ")
+		b.WriteString(fmt.Sprintf("%s    </ul>\n%s  </details>\n%s</li>\n", indent, indent, indent))
+	}
+}
+
+// navLink renders an item's anchor, marking it as the current page if it is.
+func navLink(item NavItem, currentPath, basePath string) string {
+	if item.External {
+		return fmt.Sprintf("<a href=\"%s\" rel=\"external\">%s</a>", html.EscapeString(item.Path), html.EscapeString(item.Title))
+	}
+	aria := ""
+	href := basePath + item.Path
+	if href == currentPath {
+		aria = ` aria-current="page"`
+	}
+	return fmt.Sprintf("<a href=\"%s\"%s>%s</a>", html.EscapeString(href), aria, html.EscapeString(item.Title))
+}
+
+// navContains reports whether item or any of its descendants is the current page.
+func navContains(item NavItem, currentPath, basePath string) bool {
+	if item.Path != "" && !item.External && basePath+item.Path == currentPath {
+		return true
+	}
+	for _, child := range item.Children {
+		if navContains(child, currentPath, basePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// markNav returns a copy of items with Active set on the current page and
+// Ancestor on every section above it, for templates that render the nav
+// themselves.
+func markNav(items []NavItem, currentPath, basePath string) []NavItem {
+	if items == nil {
+		return nil
+	}
+	marked := make([]NavItem, len(items))
+	for i, item := range items {
+		item.Children = markNav(item.Children, currentPath, basePath)
+		item.Active = item.Path != "" && !item.External && basePath+item.Path == currentPath
+		for _, child := range item.Children {
+			if child.Active || child.Ancestor {
+				item.Ancestor = true
+				break
 			}
-			b.WriteString("      </ul>\n    </details>\n  </li>\n")
-		} else {
-			// Top-level page
-			aria := ""
-			href := basePath + item.Path
-			if href == currentPath {
-				aria = ` aria-current="page"`
+		}
+		marked[i] = item
+	}
+	return marked
+}
+
+// navBreadcrumbs returns the trail from the top level down to the active
+// item of a marked tree, without children, or nil if the page isn't in it.
+func navBreadcrumbs(items []NavItem) []NavItem {
+	for _, item := range items {
+		if item.Active || item.Ancestor {
+			crumb := item
+			crumb.Children = nil
+			if item.Active {
+				return []NavItem{crumb}
 			}
-			b.WriteString(fmt.Sprintf("  <li><a href=\"%s\"%s>%s</a></li>\n",
-				html.EscapeString(href), aria, html.EscapeString(item.Title)))
+			return append([]NavItem{crumb}, navBreadcrumbs(item.Children)...)
 		}
 	}
+	return nil
+}
 
-	b.WriteString("</ul>\n</nav>\n")
-	return b.String()
+// navPageOrder flattens the tree into the URL paths of its pages in reading
+// order (a section's own page before its children), skipping separators,
+// external links, and repeats. Prev/next links follow this order.
+func navPageOrder(items []NavItem) []string {
+	var order []string
+	seen := make(map[string]bool)
+	var walk func(items []NavItem)
+	walk = func(items []NavItem) {
+		for _, item := range items {
+			if item.Path != "" && !item.External && !seen[item.Path] {
+				seen[item.Path] = true
+				order = append(order, item.Path)
+			}
+			walk(item.Children)
+		}
+	}
+	walk(items)
+	return order
 }
 
 // Built-in SVG icons for sidebar links.
@@ -191,6 +515,14 @@ func linkIcon(name string) string {
 	return ""
 }
 
+// navTitle is the sidebar label for a page: nav_title if set, else its title.
+func navTitle(p Page) string {
+	if p.Frontmatter.NavTitle != "" {
+		return p.Frontmatter.NavTitle
+	}
+	return pageTitle(p)
+}
+
 func pageTitle(p Page) string {
 	if p.Frontmatter.Title != "" {
 		return p.Frontmatter.Title
@@ -58,12 +58,12 @@ func TestBuildNavSections(t *testing.T) {
 }
 
 func TestBuildNavDateSortedSection(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "index.md", Frontmatter: Frontmatter{Title: "Home"}},
 		{RelPath: "posts/older.md", Frontmatter: Frontmatter{Title: "Older Post", Date: "2026-01-01"}},
 		{RelPath: "posts/newest.md", Frontmatter: Frontmatter{Title: "Newest Post", Date: "2026-03-18"}},
 		{RelPath: "posts/middle.md", Frontmatter: Frontmatter{Title: "Middle Post", Date: "2026-02-15"}},
-	}
+	})
 
 	nav := BuildNav(pages)
 
@@ -92,11 +92,11 @@ func TestBuildNavDateSortedSection(t *testing.T) {
 }
 
 func TestBuildNavMixedDatedUndatedSection(t *testing.T) {
-	pages := []Page{
+	pages := withDates([]Page{
 		{RelPath: "posts/undated.md", Frontmatter: Frontmatter{Title: "Undated Post"}},
 		{RelPath: "posts/newer.md", Frontmatter: Frontmatter{Title: "Newer Post", Date: "2026-03-18"}},
 		{RelPath: "posts/older.md", Frontmatter: Frontmatter{Title: "Older Post", Date: "2026-01-01"}},
-	}
+	})
 
 	nav := BuildNav(pages)
 	if len(nav) != 1 {
@@ -198,3 +198,249 @@ func TestBuildNavChildrenFalse(t *testing.T) {
 		t.Errorf("changelog path = %q, want /changelog/", changelog.Path)
 	}
 }
+
+func TestBuildNavNestedSections(t *testing.T) {
+	pages := []Page{
+		{RelPath: "01-guide/index.md", Frontmatter: Frontmatter{Title: "Guide"}},
+		{RelPath: "01-guide/01-intro.md", Frontmatter: Frontmatter{Title: "Intro"}},
+		{RelPath: "01-guide/02-advanced/index.md", Frontmatter: Frontmatter{Title: "Advanced"}},
+		{RelPath: "01-guide/02-advanced/01-caching.md", Frontmatter: Frontmatter{Title: "Caching"}},
+		{RelPath: "01-guide/02-advanced/02-deep/01-internals.md", Frontmatter: Frontmatter{Title: "Internals"}},
+		{RelPath: "01-guide/03-deploy.md", Frontmatter: Frontmatter{Title: "Deploy"}},
+	}
+
+	nav := BuildNav(pages)
+	if len(nav) != 1 {
+		t.Fatalf("expected 1 section, got %d", len(nav))
+	}
+
+	guide := nav[0]
+	if guide.Path != "/guide/" {
+		t.Errorf("guide path = %q, want /guide/ (from index.md)", guide.Path)
+	}
+
+	// Pages and subsections interleave by name
+	var titles []string
+	for _, c := range guide.Children {
+		titles = append(titles, c.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Intro,Advanced,Deploy" {
+		t.Fatalf("guide children = %s, want Intro,Advanced,Deploy", got)
+	}
+
+	advanced := guide.Children[1]
+	if advanced.Path != "/guide/advanced/" || len(advanced.Children) != 2 {
+		t.Fatalf("advanced: path=%q children=%d, want /guide/advanced/ with 2", advanced.Path, len(advanced.Children))
+	}
+	deep := advanced.Children[1]
+	if deep.Title != "Deep" || deep.Path != "" {
+		t.Errorf("deep: title=%q path=%q, want Deep with no index", deep.Title, deep.Path)
+	}
+	if len(deep.Children) != 1 || deep.Children[0].Path != "/guide/advanced/deep/internals/" {
+		t.Errorf("deep children = %+v", deep.Children)
+	}
+}
+
+func TestRenderNavOpensOnlyCurrentBranch(t *testing.T) {
+	items := []NavItem{
+		{Title: "Guide", Path: "/guide/", Children: []NavItem{
+			{Title: "Intro", Path: "/guide/intro/"},
+			{Title: "Advanced", Children: []NavItem{
+				{Title: "Caching", Path: "/guide/advanced/caching/"},
+			}},
+		}},
+		{Title: "Reference", Children: []NavItem{
+			{Title: "CLI", Path: "/reference/cli/"},
+		}},
+	}
+
+	html := RenderNav(items, "/site/guide/advanced/caching/", "/site", nil)
+
+	if n := strings.Count(html, "<details open>"); n != 2 {
+		t.Errorf("expected Guide and Advanced open, got %d open sections:\n%s", n, html)
+	}
+	if n := strings.Count(html, "<details>"); n != 1 {
+		t.Errorf("expected Reference closed, got %d closed sections:\n%s", n, html)
+	}
+	if !strings.Contains(html, `<summary><a href="/site/guide/">Guide</a></summary>`) {
+		t.Errorf("section with index should link its summary:\n%s", html)
+	}
+	if !strings.Contains(html, `<a href="/site/guide/advanced/caching/" aria-current="page">`) {
+		t.Errorf("expected nested page marked current:\n%s", html)
+	}
+}
+
+func navTitles(items []NavItem) string {
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestBuildNavWeightTitleHidden(t *testing.T) {
+	pages := []Page{
+		{RelPath: "guide/a.md", Frontmatter: Frontmatter{Title: "A"}},
+		{RelPath: "guide/b.md", Frontmatter: Frontmatter{Title: "B", Weight: 2}},
+		{RelPath: "guide/c.md", Frontmatter: Frontmatter{Title: "Configuration Reference", NavTitle: "Config", Weight: 1}},
+		{RelPath: "guide/d.md", Frontmatter: Frontmatter{Title: "D", NavHidden: true}},
+		{RelPath: "guide/sub/index.md", Frontmatter: Frontmatter{Title: "Sub", Weight: 3, NavTitle: "Subsection"}},
+		{RelPath: "guide/sub/e.md", Frontmatter: Frontmatter{Title: "E"}},
+		{RelPath: "guide/secret/index.md", Frontmatter: Frontmatter{NavHidden: true}},
+		{RelPath: "guide/secret/f.md", Frontmatter: Frontmatter{Title: "F"}},
+		{RelPath: "404.md", Frontmatter: Frontmatter{Title: "Not Found", NavHidden: true}},
+	}
+
+	nav := BuildNav(pages)
+	if got := navTitles(nav); got != "Guide" {
+		t.Fatalf("root = %s, want Guide (404 hidden)", got)
+	}
+	if got := navTitles(nav[0].Children); got != "Config,B,Subsection,A" {
+		t.Errorf("guide children = %s, want Config,B,Subsection,A", got)
+	}
+}
+
+func TestBuildNavOrder(t *testing.T) {
+	section := func(order string) []Page {
+		return withDates([]Page{
+			{RelPath: "posts/index.md", Frontmatter: Frontmatter{NavOrder: order}},
+			{RelPath: "posts/01-zebra.md", Frontmatter: Frontmatter{Title: "Zebra", Date: "2026-01-01", Weight: 2}},
+			{RelPath: "posts/02-apple.md", Frontmatter: Frontmatter{Title: "apple", Date: "2026-03-01"}},
+			{RelPath: "posts/03-mango.md", Frontmatter: Frontmatter{Title: "Mango", Weight: 1}},
+		})
+	}
+
+	tests := []struct {
+		order string
+		want  string
+	}{
+		{"", "Mango,Zebra,apple"},
+		{"date", "apple,Zebra,Mango"},
+		{"title", "apple,Mango,Zebra"},
+		{"weight", "Mango,Zebra,apple"},
+	}
+	for _, tt := range tests {
+		nav := BuildNav(section(tt.order))
+		if got := navTitles(nav[0].Children); got != tt.want {
+			t.Errorf("nav_order %q: children = %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestBuildExplicitNav(t *testing.T) {
+	pages := []Page{
+		{RelPath: "index.md", Frontmatter: Frontmatter{Title: "Home"}},
+		{RelPath: "01-guide/01-intro.md", Frontmatter: Frontmatter{Title: "Introduction", NavTitle: "Intro"}},
+		{RelPath: "02-reference/cli.md", Frontmatter: Frontmatter{Title: "CLI"}},
+		{RelPath: "02-reference/api.md", Frontmatter: Frontmatter{Title: "API"}},
+		{RelPath: "404.md", Frontmatter: Frontmatter{Title: "Not Found", NavHidden: true}},
+	}
+	entries := []NavEntry{
+		{Page: "01-guide/01-intro.md"},
+		{Separator: true},
+		{Title: "Tools", Children: []NavEntry{
+			{Page: "[[cli]]", Title: "Command line"},
+			{Title: "GitHub", URL: "https://github.com/oddship/moat"},
+		}},
+	}
+
+	nav, orphans, err := BuildExplicitNav(entries, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := navTitles(nav); got != "Intro,,Tools" {
+		t.Fatalf("nav = %s, want Intro,,Tools", got)
+	}
+	if !nav[1].Separator {
+		t.Error("second item should be a separator")
+	}
+	tools := nav[2].Children
+	if tools[0].Title != "Command line" || tools[0].Path != "/reference/cli/" {
+		t.Errorf("wikilink entry = %+v", tools[0])
+	}
+	if !tools[1].External {
+		t.Error("absolute URL should be external")
+	}
+	if len(orphans) != 1 || orphans[0] != "02-reference/api.md" {
+		t.Errorf("orphans = %v, want [02-reference/api.md]", orphans)
+	}
+}
+
+func TestBuildExplicitNavReportsAllMissingPages(t *testing.T) {
+	pages := []Page{{RelPath: "intro.md"}}
+	entries := []NavEntry{
+		{Page: "intro.md"},
+		{Page: "missing.md"},
+		{Title: "Group", Children: []NavEntry{{Page: "[[Nowhere]]"}}},
+		{Page: "intro.md", URL: "/intro/"},
+	}
+
+	_, _, err := BuildExplicitNav(entries, pages)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`nav[1]: page "missing.md" not found`,
+		`nav[2][0]: page "[[Nowhere]]" not found`,
+		`nav[3]: set only one of page, url, or separator`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestRenderNavSeparatorAndExternal(t *testing.T) {
+	items := []NavItem{
+		{Title: "Intro", Path: "/intro/"},
+		{Separator: true},
+		{Title: "GitHub", Path: "https://github.com/oddship/moat", External: true},
+	}
+
+	html := RenderNav(items, "/site/intro/", "/site", nil)
+
+	if !strings.Contains(html, `<li role="separator"><hr></li>`) {
+		t.Errorf("missing separator:\n%s", html)
+	}
+	if !strings.Contains(html, `<a href="https://github.com/oddship/moat" rel="external">GitHub</a>`) {
+		t.Errorf("external link should not get base path:\n%s", html)
+	}
+}
+
+func TestMarkNavBreadcrumbsAndOrder(t *testing.T) {
+	items := []NavItem{
+		{Title: "About", Path: "/about/"},
+		{Title: "Guide", Path: "/guide/", Children: []NavItem{
+			{Title: "Intro", Path: "/guide/intro/"},
+			{Title: "Advanced", Children: []NavItem{
+				{Title: "Caching", Path: "/guide/advanced/caching/"},
+			}},
+		}},
+		{Separator: true},
+		{Title: "GitHub", Path: "https://github.com/oddship/moat", External: true},
+		{Title: "About again", Path: "/about/"},
+	}
+
+	marked := markNav(items, "/site/guide/advanced/caching/", "/site")
+	if !marked[1].Ancestor || !marked[1].Children[1].Ancestor || !marked[1].Children[1].Children[0].Active {
+		t.Errorf("expected Guide > Advanced ancestors and Caching active: %+v", marked[1])
+	}
+	if marked[0].Active || marked[0].Ancestor || marked[1].Children[0].Active {
+		t.Error("unrelated items should not be marked")
+	}
+	if items[1].Ancestor {
+		t.Error("markNav must not modify the shared tree")
+	}
+
+	if got := navTitles(navBreadcrumbs(marked)); got != "Guide,Advanced,Caching" {
+		t.Errorf("breadcrumbs = %s, want Guide,Advanced,Caching", got)
+	}
+	if crumbs := navBreadcrumbs(markNav(items, "/elsewhere/", "")); crumbs != nil {
+		t.Errorf("page outside nav should have no breadcrumbs, got %v", crumbs)
+	}
+
+	order := strings.Join(navPageOrder(items), ",")
+	if order != "/about/,/guide/,/guide/intro/,/guide/advanced/caching/" {
+		t.Errorf("page order = %s", order)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Paginator is one page of a paginated list of pages: the pages below a
+// section's index.md that sets paginate, or the pages with a term.
+type Paginator struct {
+	Items      []PageMeta // Pages on this page of the list
+	PageNumber int        // 1-based
+	PageSize   int        // Items per page; 0 when every item is on one page
+	TotalPages int
+	TotalItems int
+	URL        string // This page of the list (includes base_path)
+	First      string // URL of the first page
+	Last       string // URL of the last page
+	Prev       string // URL of the previous page, empty on the first
+	Next       string // URL of the next page, empty on the last
+}
+
+// paginate splits items into pages of size items each and returns a
+// Paginator for every page; a size of 0 or less puts them all on one.
+// There is always at least one page, even without items. The first page is
+// at firstURL, page N at firstURL + "page/N/".
+func paginate(items []PageMeta, size int, firstURL string) []Paginator {
+	if size < 0 {
+		size = 0
+	}
+	total := 1
+	if size > 0 && len(items) > size {
+		total = (len(items) + size - 1) / size
+	}
+
+	pagers := make([]Paginator, total)
+	for i := range pagers {
+		p := &pagers[i]
+		p.PageNumber = i + 1
+		p.PageSize = size
+		p.TotalPages = total
+		p.TotalItems = len(items)
+		p.URL = pagerURL(firstURL, i+1)
+		p.First = firstURL
+		p.Last = pagerURL(firstURL, total)
+		if i > 0 {
+			p.Prev = pagerURL(firstURL, i)
+		}
+		if i < total-1 {
+			p.Next = pagerURL(firstURL, i+2)
+		}
+		p.Items = items
+		if size > 0 {
+			end := min((i+1)*size, len(items))
+			p.Items = items[i*size : end]
+		}
+	}
+	return pagers
+}
+
+// pagerURL returns the URL of page n of a list whose first page is at firstURL.
+func pagerURL(firstURL string, n int) string {
+	if n <= 1 {
+		return firstURL
+	}
+	return fmt.Sprintf("%spage/%d/", firstURL, n)
+}
+
+// sectionPagers returns the paginators for every section index.md that sets
+// paginate, keyed by RelPath. A section lists the pages in it and its
+// subsections, in .Pages order.
+func sectionPagers(pages []Page, metas []PageMeta, basePath string) map[string][]Paginator {
+	pagers := make(map[string][]Paginator)
+	for _, p := range pages {
+		if filepath.Base(p.RelPath) != "index.md" || p.Frontmatter.Paginate <= 0 {
+			continue
+		}
+		url := basePath + pageURL(p)
+		_, section := pageSection(p.RelPath)
+		var items []PageMeta
+		for _, m := range metas {
+			if m.URL == url {
+				continue
+			}
+			if section != "" && m.SectionPath != section && !strings.HasPrefix(m.SectionPath, section+"/") {
+				continue
+			}
+			items = append(items, m)
+		}
+		pagers[p.RelPath] = paginate(items, p.Frontmatter.Paginate, url)
+	}
+	return pagers
+}
+
+// checkPagerCollisions reports a later page of a paginated list whose URL
+// is also a page's.
+func checkPagerCollisions(pages []Page, pagers map[string][]Paginator, basePath string) error {
+	byURL := make(map[string]string, len(pages))
+	for _, p := range pages {
+		byURL[basePath+pageURL(p)] = filepath.ToSlash(p.RelPath)
+	}
+	var report []string
+	for _, p := range pages {
+		for _, pager := range pagers[p.RelPath] {
+			if other, ok := byURL[pager.URL]; ok && pager.PageNumber > 1 {
+				report = append(report, fmt.Sprintf("  %s is claimed by:\n    %s (page %d)\n    %s", pager.URL, filepath.ToSlash(p.RelPath), pager.PageNumber, other))
+			}
+		}
+	}
+	if len(report) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d URL collision(s):\n%s", len(report), strings.Join(report, "\n"))
+}
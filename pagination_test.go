@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []PageMeta{{Title: "A"}, {Title: "B"}, {Title: "C"}, {Title: "D"}, {Title: "E"}}
+
+	pagers := paginate(items, 2, "/docs/blog/")
+	if len(pagers) != 3 {
+		t.Fatalf("got %d pages, want 3", len(pagers))
+	}
+	tests := []struct {
+		items, url, prev, next string
+	}{
+		{"A,B", "/docs/blog/", "", "/docs/blog/page/2/"},
+		{"C,D", "/docs/blog/page/2/", "/docs/blog/", "/docs/blog/page/3/"},
+		{"E", "/docs/blog/page/3/", "/docs/blog/page/2/", ""},
+	}
+	for i, tt := range tests {
+		p := pagers[i]
+		if got := pageTitles(p.Items); got != tt.items || p.URL != tt.url || p.Prev != tt.prev || p.Next != tt.next {
+			t.Errorf("page %d = %s at %s, prev %q, next %q; want %s at %s, prev %q, next %q",
+				i+1, got, p.URL, p.Prev, p.Next, tt.items, tt.url, tt.prev, tt.next)
+		}
+		if p.PageNumber != i+1 || p.TotalPages != 3 || p.TotalItems != 5 || p.PageSize != 2 ||
+			p.First != "/docs/blog/" || p.Last != "/docs/blog/page/3/" {
+			t.Errorf("page %d = %+v", i+1, p)
+		}
+	}
+
+	// Without a size, or without items, there's a single page
+	if pagers := paginate(items, 0, "/tags/go/"); len(pagers) != 1 || len(pagers[0].Items) != 5 || pagers[0].Next != "" {
+		t.Errorf("size 0 = %+v", pagers)
+	}
+	if pagers := paginate(nil, 10, "/blog/"); len(pagers) != 1 || pagers[0].TotalPages != 1 || pagers[0].Last != "/blog/" {
+		t.Errorf("no items = %+v", pagers)
+	}
+}
@@ -2,19 +2,27 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 // ShortcodeContext is passed to shortcode templates.
 type ShortcodeContext struct {
-	Inner template.HTML     // Rendered inner content (markdown → HTML)
-	Args  map[string]string // Named arguments
-	Page  *TemplateData     // Parent page data
+	Name       string             // Shortcode name, e.g. "note"
+	Inner      template.HTML      // Rendered inner content (markdown → HTML)
+	Args       map[string]string  // Named arguments; bare flags are "true"
+	Positional []string           // Arguments without a name, in order
+	Nested     []ShortcodeContext // Shortcodes called directly inside this one, in order
+	Page       *TemplateData      // Parent page data
+
+	files *shortcodeFiles
 }
 
 // Get returns a named argument or empty string.
@@ -22,7 +30,76 @@ func (sc ShortcodeContext) Get(key string) string {
 	return sc.Args[key]
 }
 
-// SectionPages returns pages matching a section name.
+// Pos returns the i-th positional argument or empty string.
+func (sc ShortcodeContext) Pos(i int) string {
+	if i < 0 || i >= len(sc.Positional) {
+		return ""
+	}
+	return sc.Positional[i]
+}
+
+// GetBool reports whether a named argument is set to a true value
+// ("true", "1", "yes", ...), or given as a bare flag.
+func (sc ShortcodeContext) GetBool(key string) bool {
+	switch strings.ToLower(sc.Args[key]) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// GetInt returns a named argument as an integer, or 0 if it is missing or
+// not a number.
+func (sc ShortcodeContext) GetInt(key string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(sc.Args[key]))
+	return n
+}
+
+// RelURL is the relURL template function as a method, for shortcodes
+// written before there was one.
+func (sc ShortcodeContext) RelURL(u string) string {
+	f := &templateFuncs{}
+	if sc.Page != nil {
+		f.basePath = strings.TrimRight(sc.Page.BasePath, "/")
+	}
+	return f.relURL(u)
+}
+
+// ReadFile returns the content of a file under the source directory,
+// without trailing newlines. path is relative to the source directory;
+// paths that lead outside it are refused.
+func (sc ShortcodeContext) ReadFile(path string) (template.HTML, error) {
+	if sc.files == nil {
+		return "", fmt.Errorf("reading %s: no source directory", path)
+	}
+	data, err := sc.files.read(path)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(strings.TrimRight(string(data), "\r\n")), nil
+}
+
+// Fence returns a code fence of tildes that content can't close: one longer
+// than the longest run of tildes or backticks in it, and at least three.
+func (sc ShortcodeContext) Fence(content any) string {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range toText(content) {
+		if (r == '~' || r == '`') && r == prev {
+			run++
+		} else if r == '~' || r == '`' {
+			run = 1
+		} else {
+			run = 0
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+	return strings.Repeat("~", max(3, longest+1))
+}
+
+// SectionPages returns pages in a section and its subsections. section is a
+// top-level name ("guide") or a nested path ("guide/advanced").
 // If section is empty, returns all pages. Excludes the current page.
 func (sc ShortcodeContext) SectionPages(section string) []PageMeta {
 	if sc.Page == nil {
@@ -33,7 +110,7 @@ func (sc ShortcodeContext) SectionPages(section string) []PageMeta {
 		if p.URL == sc.Page.CurrentPath {
 			continue // skip current page
 		}
-		if section != "" && p.Section != section {
+		if section != "" && p.SectionPath != section && !strings.HasPrefix(p.SectionPath, section+"/") {
 			continue
 		}
 		result = append(result, p)
@@ -41,15 +118,57 @@ func (sc ShortcodeContext) SectionPages(section string) []PageMeta {
 	return result
 }
 
+// shortcodeFiles gives shortcodes read access to files under the source
+// directory and records what they read, so the build cache can re-render a
+// page when an included file changes.
+type shortcodeFiles struct {
+	src   string
+	reads map[string]string // Slash-separated path → digest of content
+}
+
+func (f *shortcodeFiles) read(path string) ([]byte, error) {
+	rel := strings.TrimLeft(filepath.ToSlash(path), "/")
+	root, err := os.OpenRoot(f.src)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	file, err := root.Open(filepath.FromSlash(rel))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	f.reads[filepath.ToSlash(filepath.Clean(rel))] = digest(data)
+	return data, nil
+}
+
 // shortcodeRegistry holds parsed shortcode templates.
 type shortcodeRegistry struct {
-	templates map[string]*template.Template
+	src        string // Source directory, for files read by shortcodes
+	templates  map[string]*template.Template
+	digests    map[string]string // name → digest of template source, for the build cache
+	listsPages map[string]bool   // name → whether the template reads the full page list
+}
+
+// files returns a fresh record of files read by shortcodes.
+func (reg *shortcodeRegistry) files() *shortcodeFiles {
+	return &shortcodeFiles{src: reg.src, reads: make(map[string]string)}
 }
 
 // loadShortcodes discovers shortcode templates from _shortcodes/ directory,
-// falling back to embedded defaults for any not provided.
-func loadShortcodes(src string) (*shortcodeRegistry, error) {
-	reg := &shortcodeRegistry{templates: make(map[string]*template.Template)}
+// falling back to embedded defaults for any not provided. Templates get the
+// shared function library from funcs, and every partial in partials.
+func loadShortcodes(src string, funcs *templateFuncs, partials *partialSet) (*shortcodeRegistry, error) {
+	reg := &shortcodeRegistry{
+		src:        src,
+		templates:  make(map[string]*template.Template),
+		digests:    make(map[string]string),
+		listsPages: make(map[string]bool),
+	}
 
 	// Load from source directory
 	dir := filepath.Join(src, "_shortcodes")
@@ -68,150 +187,687 @@ func loadShortcodes(src string) (*shortcodeRegistry, error) {
 				return nil, fmt.Errorf("reading shortcode %s: %w", name, err)
 			}
 
-			tmpl, err := template.New(scName).Parse(string(data))
+			tmpl, err := template.New(scName).Funcs(funcs.funcMap()).Parse(string(data))
 			if err != nil {
 				return nil, fmt.Errorf("parsing shortcode %s: %w", name, err)
 			}
 
 			reg.templates[scName] = tmpl
+			reg.digests[scName] = digest(data)
+			reg.listsPages[scName] = listsPages(data)
 			fmt.Printf("  Shortcode: %s\n", scName)
 		}
 	}
 
+	// Fill in built-in shortcodes not overridden by name
+	entries, err = listEmbeddedDir("_shortcodes")
+	if err != nil {
+		return nil, fmt.Errorf("reading built-in shortcodes: %w", err)
+	}
+	var builtin []string
+	for _, entry := range entries {
+		scName := strings.TrimSuffix(entry.Name(), ".html")
+		if _, ok := reg.templates[scName]; ok {
+			continue
+		}
+		data, err := readEmbeddedFile("_shortcodes/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading built-in shortcode %s: %w", scName, err)
+		}
+		tmpl, err := template.New(scName).Funcs(funcs.funcMap()).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing built-in shortcode %s: %w", scName, err)
+		}
+		reg.templates[scName] = tmpl
+		reg.digests[scName] = digest(data)
+		reg.listsPages[scName] = listsPages(data)
+		builtin = append(builtin, scName)
+	}
+	if len(builtin) > 0 {
+		fmt.Printf("  Shortcodes (built-in): %s\n", strings.Join(builtin, ", "))
+	}
+
+	// Every shortcode can call every partial
+	for name, tmpl := range reg.templates {
+		if err := partials.addTo(tmpl); err != nil {
+			return nil, fmt.Errorf("shortcode %s: %w", name, err)
+		}
+		reg.digests[name] = digest([]byte(reg.digests[name]), []byte(partials.digest()))
+		reg.listsPages[name] = reg.listsPages[name] || partials.listsPages()
+	}
+
 	return reg, nil
 }
 
-// Opening tag: {{< name key="val" >}}
-var reShortcodeOpen = regexp.MustCompile(`\{\{<\s*(\w+)((?:\s+\w+="[^"]*")*)\s*>\}\}`)
+// ShortcodeError reports a malformed or failing shortcode call at a
+// position in a page's markdown.
+type ShortcodeError struct {
+	File string // Source path, set by the build; empty for bare markdown
+	Line int    // 1-based
+	Col  int    // 1-based, in bytes
+	Msg  string
+}
 
-// Closing tag: {{< /name >}}
-var reShortcodeClose = regexp.MustCompile(`\{\{<\s*/(\w+)\s*>\}\}`)
+func (e *ShortcodeError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Msg)
+}
 
-// Self-closing shortcode: {{< name key="val" />}}
-var reShortcodeSelf = regexp.MustCompile(`\{\{<\s*(\w+)((?:\s+\w+="[^"]*")*)\s*/>\}\}`)
+// shortcodeErrorAt builds a ShortcodeError for byte offset off in src.
+func shortcodeErrorAt(src []byte, off int, format string, args ...any) *ShortcodeError {
+	line, col := sourcePosition(src, off)
+	return &ShortcodeError{Line: line, Col: col, Msg: fmt.Sprintf(format, args...)}
+}
 
-// Argument parser: key="value"
-var reArgs = regexp.MustCompile(`(\w+)="([^"]*)"`)
+// sourcePosition returns the 1-based line and column of byte offset off.
+func sourcePosition(src []byte, off int) (line, col int) {
+	return bytes.Count(src[:off], []byte("\n")) + 1, off - bytes.LastIndexByte(src[:off], '\n')
+}
 
-// ProcessShortcodes replaces shortcode calls in markdown source with rendered HTML.
-// Must be called BEFORE markdown rendering.
-func (reg *shortcodeRegistry) ProcessShortcodes(source []byte, page *TemplateData, resolver *pageResolver) ([]byte, error) {
-	if len(reg.templates) == 0 {
-		return source, nil
-	}
+type shortcodeTagKind int
 
-	result := source
+const (
+	tagOpen    shortcodeTagKind = iota // {{< name args >}}
+	tagClose                           // {{< /name >}}
+	tagSelf                            // {{< name args />}}
+	tagEscaped                         // {{</* ... */>}}, emitted literally
+)
 
-	// Process self-closing shortcodes first (simpler, no nesting concerns)
-	var lastErr error
-	result = reShortcodeSelf.ReplaceAllFunc(result, func(match []byte) []byte {
-		if lastErr != nil {
-			return match
-		}
+// shortcodeTag is one tag found in markdown source.
+type shortcodeTag struct {
+	kind       shortcodeTagKind
+	start, end int    // Byte range of the whole tag
+	name       string // Shortcode name (open, close, and self-closing tags)
+	args       shortcodeArgs
+	literal    string // Replacement text (escaped tags)
+	span       int    // Open tags: index distance to the matching close tag
+}
 
-		parts := reShortcodeSelf.FindSubmatch(match)
-		name := string(parts[1])
-		argsStr := string(parts[2])
+var (
+	shortcodeOpenDelim  = []byte("{{<")
+	shortcodeCloseDelim = []byte(">}}")
+	shortcodeEscOpen    = []byte("/*")
+	shortcodeEscClose   = []byte("*/>}}")
+)
 
-		tmpl, ok := reg.templates[name]
-		if !ok {
-			lastErr = fmt.Errorf("unknown shortcode: %s", name)
-			return match
+// Shortcode names: {{< name ... >}}
+var reShortcodeTagName = regexp.MustCompile(`^\w+`)
+
+// Argument names: key=..., or a bare flag
+var reShortcodeArgName = regexp.MustCompile(`^[\w-]+$`)
+
+// scanShortcodes finds shortcode tags in markdown source, in order. Tags
+// inside fenced code blocks, indented code blocks, and code spans are left
+// alone, so examples can be shown verbatim; escaped tags ({{</* ... */>}})
+// are found everywhere, including in code.
+func scanShortcodes(src []byte) ([]shortcodeTag, error) {
+	blocks := codeBlockRanges(src)
+	var tags []shortcodeTag
+
+	// escapes collects escaped tags that start in src[from:to].
+	escapes := func(from, to int) error {
+		for from < to {
+			i := bytes.Index(src[from:to], shortcodeOpenDelim)
+			if i < 0 {
+				return nil
+			}
+			start := from + i
+			if tag, ok, err := scanEscapedTag(src, start); err != nil {
+				return err
+			} else if ok {
+				tags = append(tags, tag)
+				from = tag.end
+				continue
+			}
+			from = start + len(shortcodeOpenDelim)
 		}
+		return nil
+	}
 
-		ctx := ShortcodeContext{
-			Args: parseArgs(argsStr),
-			Page: page,
+	b := 0 // Next code block at or after i
+	for i := 0; i < len(src); {
+		for b < len(blocks) && blocks[b][1] <= i {
+			b++
+		}
+		if b < len(blocks) && i >= blocks[b][0] {
+			if err := escapes(i, blocks[b][1]); err != nil {
+				return nil, err
+			}
+			if len(tags) > 0 && tags[len(tags)-1].end > blocks[b][1] {
+				i = tags[len(tags)-1].end
+			} else {
+				i = blocks[b][1]
+			}
+			continue
 		}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, ctx); err != nil {
-			lastErr = fmt.Errorf("executing shortcode %s: %w", name, err)
-			return match
+		switch {
+		case bytes.HasPrefix(src[i:], shortcodeOpenDelim):
+			tag, err := scanShortcodeTag(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tags = append(tags, tag)
+			i = tag.end
+
+		case src[i] == '\\' && i+1 < len(src) && src[i+1] == '`':
+			i += 2 // Escaped backtick, not a code span
+
+		case src[i] == '`':
+			n := backtickRun(src, i)
+			end := codeSpanEnd(src, i+n, n, blockStart(blocks, b))
+			if end < 0 {
+				i += n
+				continue
+			}
+			if err := escapes(i, end); err != nil {
+				return nil, err
+			}
+			if len(tags) > 0 && tags[len(tags)-1].end > end {
+				end = tags[len(tags)-1].end
+			}
+			i = end
+
+		default:
+			i++
 		}
+	}
+	return tags, nil
+}
 
-		return buf.Bytes()
-	})
+// scanEscapedTag reads {{</* ... */>}} at start. ok is false if the tag at
+// start is not an escaped one.
+func scanEscapedTag(src []byte, start int) (tag shortcodeTag, ok bool, err error) {
+	p := start + len(shortcodeOpenDelim)
+	for p < len(src) && (src[p] == ' ' || src[p] == '\t') {
+		p++
+	}
+	if !bytes.HasPrefix(src[p:], shortcodeEscOpen) {
+		return shortcodeTag{}, false, nil
+	}
+	inner := p + len(shortcodeEscOpen)
+	end := bytes.Index(src[inner:], shortcodeEscClose)
+	if end < 0 {
+		return shortcodeTag{}, false, shortcodeErrorAt(src, start, "escaped shortcode is missing its closing */>}}")
+	}
+	end += inner
+	return shortcodeTag{
+		kind:    tagEscaped,
+		start:   start,
+		end:     end + len(shortcodeEscClose),
+		literal: "{{<" + string(src[inner:end]) + ">}}",
+	}, true, nil
+}
 
-	if lastErr != nil {
-		return nil, lastErr
+// scanShortcodeTag reads the tag starting with {{< at start.
+func scanShortcodeTag(src []byte, start int) (shortcodeTag, error) {
+	if tag, ok, err := scanEscapedTag(src, start); ok || err != nil {
+		return tag, err
 	}
 
-	// Process block shortcodes by finding matched open/close pairs
-	result, err := reg.processBlockShortcodes(result, page, resolver)
+	// Find the closing >}}, skipping over quoted argument values
+	p := start + len(shortcodeOpenDelim)
+	var quote byte
+	for ; p < len(src); p++ {
+		c := src[p]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				p++ // Skip the escaped character
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if isShortcodeQuote(c) && startsArgValue(src[p-1]) {
+			quote = c
+		} else if bytes.HasPrefix(src[p:], shortcodeCloseDelim) {
+			break
+		}
+	}
+	if p >= len(src) {
+		return shortcodeTag{}, shortcodeErrorAt(src, start, "shortcode is missing its closing >}}")
+	}
+	tag := shortcodeTag{start: start, end: p + len(shortcodeCloseDelim)}
+
+	raw := string(src[start+len(shortcodeOpenDelim) : p])
+	body := strings.TrimSpace(raw)
+	if rest, ok := strings.CutPrefix(body, "/"); ok {
+		tag.kind = tagClose
+		tag.name = strings.TrimSpace(rest)
+		if reShortcodeTagName.FindString(tag.name) != tag.name || tag.name == "" {
+			return shortcodeTag{}, shortcodeErrorAt(src, start, "malformed closing shortcode tag %q", string(src[start:tag.end]))
+		}
+		return tag, nil
+	}
+
+	tag.kind = tagOpen
+	if rest, ok := strings.CutSuffix(body, "/"); ok {
+		tag.kind = tagSelf
+		body = strings.TrimSpace(rest)
+	}
+	tag.name = reShortcodeTagName.FindString(body)
+	argText := body[len(tag.name):]
+	if tag.name == "" || (argText != "" && !isArgSpace(argText[0])) {
+		return shortcodeTag{}, shortcodeErrorAt(src, start, "malformed shortcode tag %q", string(src[start:tag.end]))
+	}
+	args, err := parseArgs(argText)
 	if err != nil {
-		return nil, err
+		var argErr *shortcodeArgError
+		errors.As(err, &argErr)
+		argsStart := start + len(shortcodeOpenDelim) + len(raw) - len(strings.TrimLeft(raw, " \t\r\n")) + len(tag.name)
+		return shortcodeTag{}, shortcodeErrorAt(src, argsStart+argErr.off, "shortcode %q: %s", tag.name, argErr.msg)
 	}
+	tag.args = args
+	return tag, nil
+}
 
-	return result, nil
+// shortcodeArgs are the parsed arguments of one tag.
+type shortcodeArgs struct {
+	named      map[string]string
+	positional []string
 }
 
-// processBlockShortcodes finds matched {{< name >}}...{{< /name >}} pairs
-// and replaces them with rendered shortcode output.
-func (reg *shortcodeRegistry) processBlockShortcodes(source []byte, page *TemplateData, resolver *pageResolver) ([]byte, error) {
-	s := string(source)
-	closePatterns := make(map[string]*regexp.Regexp)
+// shortcodeArgError is a syntax error at byte offset off in argument text.
+type shortcodeArgError struct {
+	off int
+	msg string
+}
 
+func (e *shortcodeArgError) Error() string { return e.msg }
+
+// parseArgs parses the arguments of a shortcode tag:
+//
+//	key="value"  key='value'  key=`raw value`  key=bare
+//	"positional"  'positional'  `positional`  bare
+//
+// A bare word that is a valid name is also a flag: it is set in the named
+// arguments with the value "true". Double- and single-quoted strings may
+// escape their quote or a backslash with a backslash; backtick strings are
+// taken as written.
+func parseArgs(s string) (shortcodeArgs, error) {
+	args := shortcodeArgs{named: make(map[string]string)}
+	i := 0
 	for {
-		// Find the first opening tag
-		openLoc := reShortcodeOpen.FindStringIndex(s)
-		if openLoc == nil {
-			break
+		for i < len(s) && isArgSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return args, nil
 		}
 
-		openMatch := reShortcodeOpen.FindStringSubmatch(s[openLoc[0]:])
-		name := openMatch[1]
-		argsStr := openMatch[2]
+		if isShortcodeQuote(s[i]) {
+			v, n, err := readQuotedArg(s, i)
+			if err != nil {
+				return args, err
+			}
+			args.positional = append(args.positional, v)
+			i += n
+		} else {
+			j := i
+			for j < len(s) && !isArgSpace(s[j]) && s[j] != '=' {
+				j++
+			}
+			word := s[i:j]
+			if j < len(s) && s[j] == '=' {
+				if !reShortcodeArgName.MatchString(word) {
+					return args, &shortcodeArgError{i, fmt.Sprintf("invalid argument name %q", word)}
+				}
+				j++
+				if j >= len(s) || isArgSpace(s[j]) {
+					return args, &shortcodeArgError{i, fmt.Sprintf("argument %q has no value", word)}
+				}
+				if isShortcodeQuote(s[j]) {
+					v, n, err := readQuotedArg(s, j)
+					if err != nil {
+						return args, err
+					}
+					args.named[word] = v
+					j += n
+				} else {
+					k := j
+					for k < len(s) && !isArgSpace(s[k]) {
+						k++
+					}
+					args.named[word] = s[j:k]
+					j = k
+				}
+			} else {
+				args.positional = append(args.positional, word)
+				if _, set := args.named[word]; !set && reShortcodeArgName.MatchString(word) {
+					args.named[word] = "true"
+				}
+			}
+			i = j
+		}
 
-		// Find the matching closing tag for this name (cached per name)
-		closePattern, ok := closePatterns[name]
-		if !ok {
-			closePattern = regexp.MustCompile(`\{\{<\s*/` + regexp.QuoteMeta(name) + `\s*>\}\}`)
-			closePatterns[name] = closePattern
+		if i < len(s) && !isArgSpace(s[i]) {
+			return args, &shortcodeArgError{i, "expected a space between arguments"}
 		}
-		rest := s[openLoc[1]:]
-		closeLoc := closePattern.FindStringIndex(rest)
-		if closeLoc == nil {
-			return nil, fmt.Errorf("shortcode %q opened but never closed", name)
+	}
+}
+
+// readQuotedArg reads the quoted string starting at s[i], returning its
+// value and length including the quotes.
+func readQuotedArg(s string, i int) (string, int, error) {
+	q := s[i]
+	var b strings.Builder
+	for j := i + 1; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case c == q:
+			return b.String(), j + 1 - i, nil
+		case c == '\\' && q != '`' && j+1 < len(s) && (s[j+1] == q || s[j+1] == '\\'):
+			j++
+			b.WriteByte(s[j])
+		default:
+			b.WriteByte(c)
 		}
+	}
+	return "", 0, &shortcodeArgError{i, fmt.Sprintf("unterminated %c-quoted argument", q)}
+}
+
+func isShortcodeQuote(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
 
-		inner := rest[:closeLoc[0]]
-		fullEnd := openLoc[1] + closeLoc[1]
+func isArgSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// startsArgValue reports whether a quote after prev opens a quoted string.
+// Quotes inside a bare word, as in don't, are ordinary characters.
+func startsArgValue(prev byte) bool {
+	return isArgSpace(prev) || prev == '=' || prev == '<'
+}
 
-		tmpl, ok := reg.templates[name]
-		if !ok {
-			return nil, fmt.Errorf("unknown shortcode: %s", name)
+// codeBlockRanges returns the byte ranges of fenced and indented code
+// blocks in src, in order. Each range covers whole lines.
+func codeBlockRanges(src []byte) [][2]int {
+	var ranges [][2]int
+	add := func(start, end int) {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == start {
+			ranges[n-1][1] = end
+			return
 		}
+		ranges = append(ranges, [2]int{start, end})
+	}
 
-		// Render inner content as markdown, preserving page-aware wikilink resolution.
-		innerHTML, err := RenderMarkdownWithResolver([]byte(inner), resolver)
-		if err != nil {
-			return nil, fmt.Errorf("rendering inner content for shortcode %s: %w", name, err)
+	var (
+		fence     byte // Fence character of the open fenced block, or 0
+		fenceLen  int
+		blank     = true  // Previous line was blank (or start of input)
+		indented  = false // Previous line was indented code
+		inList    = false // Inside a list item, where indentation is content
+		lineStart int
+	)
+	for lineStart < len(src) {
+		lineEnd := len(src)
+		next := len(src)
+		if i := bytes.IndexByte(src[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i
+			next = lineEnd + 1
 		}
+		line := src[lineStart:lineEnd]
+		indent, rest := lineIndent(line)
+
+		switch {
+		case fence != 0:
+			add(lineStart, next)
+			if indent < 4 && fenceRun(rest, fence) >= fenceLen && len(bytes.TrimSpace(rest[fenceRun(rest, fence):])) == 0 {
+				fence = 0
+			}
+			blank, indented = false, false
+
+		case len(bytes.TrimSpace(line)) == 0:
+			if indented {
+				add(lineStart, next)
+			}
+			blank = true
+
+		case indent < 4 && (fenceRun(rest, '`') >= 3 || fenceRun(rest, '~') >= 3):
+			c := rest[0]
+			n := fenceRun(rest, c)
+			if c == '`' && bytes.IndexByte(rest[n:], '`') >= 0 {
+				// Not a fence: backtick info strings can't contain backticks
+				blank, indented, inList = false, false, reListItem.Match(rest)
+				break
+			}
+			fence, fenceLen = c, n
+			add(lineStart, next)
+			blank, indented = false, false
 
-		ctx := ShortcodeContext{
-			Inner: template.HTML(innerHTML),
-			Args:  parseArgs(argsStr),
-			Page:  page,
+		case indent >= 4 && !inList && (blank || indented):
+			add(lineStart, next)
+			blank, indented = false, true
+
+		default:
+			if indent < 4 {
+				inList = reListItem.Match(rest) || (inList && !blank)
+			}
+			blank, indented = false, false
 		}
+		lineStart = next
+	}
+	return ranges
+}
 
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, ctx); err != nil {
-			return nil, fmt.Errorf("executing shortcode %s: %w", name, err)
+// List item markers: "- ", "* ", "+ ", "1. ", "1) "
+var reListItem = regexp.MustCompile(`^(?:[-*+]|\d{1,9}[.)])(?:[ \t]|$)`)
+
+// lineIndent returns a line's indentation width (tabs count to the next
+// multiple of four) and the line without it.
+func lineIndent(line []byte) (int, []byte) {
+	width := 0
+	for i, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width, line[i:]
 		}
+	}
+	return width, nil
+}
 
-		// Replace the full shortcode call with rendered output
-		s = s[:openLoc[0]] + buf.String() + s[fullEnd:]
+// fenceRun returns the number of leading c bytes in b.
+func fenceRun(b []byte, c byte) int {
+	n := 0
+	for n < len(b) && b[n] == c {
+		n++
 	}
+	return n
+}
+
+func backtickRun(src []byte, i int) int {
+	return fenceRun(src[i:], '`')
+}
 
-	return []byte(s), nil
+// blockStart returns the start of code block b, or -1 when there are no
+// more blocks.
+func blockStart(blocks [][2]int, b int) int {
+	if b < len(blocks) {
+		return blocks[b][0]
+	}
+	return -1
 }
 
-func parseArgs(s string) map[string]string {
-	args := make(map[string]string)
-	matches := reArgs.FindAllStringSubmatch(s, -1)
-	for _, m := range matches {
-		args[m[1]] = m[2]
+// codeSpanEnd returns the end of the code span whose opening run of n
+// backticks ends at from, or -1 if it is never closed. Spans don't cross
+// blank lines or run into a code block starting at limit (-1 for none).
+func codeSpanEnd(src []byte, from, n, limit int) int {
+	end := len(src)
+	if limit >= 0 {
+		end = limit
+	}
+	for i := from; i < end; {
+		switch {
+		case src[i] == '`':
+			run := backtickRun(src[:end], i)
+			if run == n {
+				return i + run
+			}
+			i += run
+		case src[i] == '\n' && len(bytes.TrimSpace(lineAt(src[:end], i+1))) == 0:
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// lineAt returns the line starting at offset i, without its newline.
+func lineAt(src []byte, i int) []byte {
+	if i >= len(src) {
+		return []byte("x") // End of input is not a blank line
+	}
+	if j := bytes.IndexByte(src[i:], '\n'); j >= 0 {
+		return src[i : i+j]
+	}
+	return src[i:]
+}
+
+// ProcessShortcodes replaces shortcode calls in markdown source with rendered HTML.
+// Must be called BEFORE markdown rendering.
+// Inner content of block shortcodes is rendered with md, so it resolves
+// links the same way as the rest of the page. Errors are *ShortcodeError
+// with a position relative to source.
+func (reg *shortcodeRegistry) ProcessShortcodes(source []byte, page *TemplateData, md markdownRenderer) ([]byte, error) {
+	return reg.process(source, page, md, reg.files(), reg.templates)
+}
+
+// process is ProcessShortcodes with files recording what shortcodes read,
+// running the given copies of the registry's templates.
+func (reg *shortcodeRegistry) process(source []byte, page *TemplateData, md markdownRenderer, files *shortcodeFiles, templates map[string]*template.Template) ([]byte, error) {
+	if !bytes.Contains(source, shortcodeOpenDelim) {
+		return source, nil
+	}
+	tags, err := scanShortcodes(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := pairShortcodes(source, tags); err != nil {
+		return nil, err
+	}
+	run := &shortcodeRun{templates: templates, source: source, page: page, md: md, files: files}
+	out, _, err := run.expand(tags, 0, len(source))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// pairShortcodes matches each open tag with its closing tag, innermost
+// first, so shortcodes can nest, including inside one of the same name.
+func pairShortcodes(source []byte, tags []shortcodeTag) error {
+	var open []int // Indexes of tags still waiting for their close
+	for k, tag := range tags {
+		switch tag.kind {
+		case tagOpen:
+			open = append(open, k)
+		case tagClose:
+			if len(open) == 0 {
+				return shortcodeErrorAt(source, tag.start, "closing tag for %q has no matching opening tag", tag.name)
+			}
+			top := open[len(open)-1]
+			if tags[top].name != tag.name {
+				line, col := sourcePosition(source, tags[top].start)
+				return shortcodeErrorAt(source, tag.start, "closing tag for %q does not match %q opened at %d:%d",
+					tag.name, tags[top].name, line, col)
+			}
+			tags[top].span = k - top
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		tag := tags[open[len(open)-1]]
+		return shortcodeErrorAt(source, tag.start, "shortcode %q opened but never closed", tag.name)
+	}
+	return nil
+}
+
+// shortcodeRun holds the state of processing one page's shortcodes.
+type shortcodeRun struct {
+	templates map[string]*template.Template
+	source    []byte
+	page      *TemplateData
+	md        markdownRenderer
+	files     *shortcodeFiles
+}
+
+// expand renders source[from:to], whose tags are given in order. It also
+// returns the contexts of the shortcodes it ran, for the enclosing block's
+// Nested.
+func (run *shortcodeRun) expand(tags []shortcodeTag, from, to int) ([]byte, []ShortcodeContext, error) {
+	var out bytes.Buffer
+	var called []ShortcodeContext
+	pos := from
+	for k := 0; k < len(tags); k++ {
+		tag := tags[k]
+		out.Write(run.source[pos:tag.start])
+		pos = tag.end
+
+		switch tag.kind {
+		case tagEscaped:
+			out.WriteString(tag.literal)
+
+		case tagSelf:
+			ctx, err := run.execute(&out, tag, "", nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			called = append(called, ctx)
+
+		case tagOpen:
+			m := k + tag.span
+			if _, ok := run.templates[tag.name]; !ok {
+				return nil, nil, shortcodeErrorAt(run.source, tag.start, "unknown shortcode %q", tag.name)
+			}
+
+			// Expand nested shortcodes, then render the inner content as
+			// markdown, preserving page-aware link resolution.
+			inner, nested, err := run.expand(tags[k+1:m], tag.end, tags[m].start)
+			if err != nil {
+				return nil, nil, err
+			}
+			innerHTML, err := run.md.render(inner)
+			if err != nil {
+				return nil, nil, fmt.Errorf("rendering inner content for shortcode %s: %w", tag.name, err)
+			}
+			ctx, err := run.execute(&out, tag, template.HTML(innerHTML), nested)
+			if err != nil {
+				return nil, nil, err
+			}
+			called = append(called, ctx)
+			pos = tags[m].end
+			k = m
+		}
+	}
+	out.Write(run.source[pos:to])
+	return out.Bytes(), called, nil
+}
+
+// execute runs the template for tag and writes its output to w.
+func (run *shortcodeRun) execute(w *bytes.Buffer, tag shortcodeTag, inner template.HTML, nested []ShortcodeContext) (ShortcodeContext, error) {
+	ctx := ShortcodeContext{
+		Name:       tag.name,
+		Inner:      inner,
+		Args:       tag.args.named,
+		Positional: tag.args.positional,
+		Nested:     nested,
+		Page:       run.page,
+		files:      run.files,
+	}
+	tmpl, ok := run.templates[tag.name]
+	if !ok {
+		return ctx, shortcodeErrorAt(run.source, tag.start, "unknown shortcode %q", tag.name)
+	}
+	if err := tmpl.Execute(w, ctx); err != nil {
+		return ctx, shortcodeErrorAt(run.source, tag.start, "executing shortcode %s: %v", tag.name, err)
 	}
-	return args
+	return ctx, nil
 }
@@ -17,7 +17,7 @@ func TestBlockShortcodeInnerContentResolvesWikilinks(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	reg, err := loadShortcodes(dir)
+	reg, err := loadShortcodes(dir, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -88,3 +88,275 @@ func TestSectionPagesFiltering(t *testing.T) {
 		t.Error("expected nil for nil page")
 	}
 }
+
+func newTestShortcodes(t *testing.T) *shortcodeRegistry {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_shortcodes", "note.html"), `<div class="note">{{ .Inner }}</div>`)
+	writeTestFile(t, filepath.Join(dir, "_shortcodes", "badge.html"), `<span class="badge">{{ .Get "text" }}</span>`)
+	reg, err := loadShortcodes(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestShortcodesIgnoredInCode(t *testing.T) {
+	reg := newTestShortcodes(t)
+	resolver := newPageResolver(nil, "")
+
+	source := "Inline `{{< badge text=\"a\" />}}` stays.\n\n" +
+		"```\n{{< note >}}\n```\n\n" +
+		"~~~~md\n{{< /note >}}\n~~~~\n\n" +
+		"Para.\n\n    {{< badge text=\"b\" />}}\n\n" +
+		"- item\n\n    {{< badge text=\"c\" />}}\n"
+	out, err := reg.ProcessShortcodes([]byte(source), &TemplateData{}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"`{{< badge text=\"a\" />}}`",
+		"```\n{{< note >}}\n```",
+		"~~~~md\n{{< /note >}}\n~~~~",
+		"    {{< badge text=\"b\" />}}",
+		`<span class="badge">c</span>`, // Indented list content is not code
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestShortcodeEscapes(t *testing.T) {
+	reg := newTestShortcodes(t)
+	resolver := newPageResolver(nil, "")
+
+	source := "{{</* note */>}}x{{</* /note */>}} and {{</* badge text=\"a\" /*/>}}\n\n" +
+		"```\n{{</* note */>}}\n```\n"
+	out, err := reg.ProcessShortcodes([]byte(source), &TemplateData{}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{{< note >}}x{{< /note >}} and {{< badge text=\"a\" />}}\n\n```\n{{< note >}}\n```\n"
+	if string(out) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestShortcodeErrorPositions(t *testing.T) {
+	reg := newTestShortcodes(t)
+	resolver := newPageResolver(nil, "")
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a\n  {{< note >}}\nb\n", `2:3: shortcode "note" opened but never closed`},
+		{"{{< /note >}}", `1:1: closing tag for "note" has no matching opening tag`},
+		{"x {{< missing />}}", `1:3: unknown shortcode "missing"`},
+		{"\n\n{{< note", `3:1: shortcode is missing its closing >}}`},
+	}
+	for _, tt := range tests {
+		_, err := reg.ProcessShortcodes([]byte(tt.source), &TemplateData{}, resolver)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ProcessShortcodes(%q) error = %v, want %s", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestBuildReportsShortcodeErrorInSourceFile(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "_shortcodes", "note.html"), `{{ .Inner }}`)
+	writeTestFile(t, filepath.Join(src, "page.md"), "---\ntitle: Page\n---\n\nText.\n\n   {{< note >}}\n")
+
+	err := Build(src, t.TempDir(), Config{})
+	if err == nil || !strings.Contains(err.Error(), `page.md:7:4: shortcode "note" opened but never closed`) {
+		t.Fatalf("expected file:line:col error, got %v", err)
+	}
+}
+
+func TestShortcodesNestSameName(t *testing.T) {
+	reg := newTestShortcodes(t)
+	resolver := newPageResolver(nil, "")
+
+	source := "{{< note >}}\nouter\n\n{{< note >}}\ninner\n{{< /note >}}\n\nafter\n{{< /note >}}\n"
+	out, err := reg.ProcessShortcodes([]byte(source), &TemplateData{}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(strings.Fields(string(out)), "")
+	want := `<divclass="note"><p>outer</p><divclass="note"><p>inner</p></div><p>after</p></div>`
+	if got != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	_, err = reg.ProcessShortcodes([]byte("{{< note >}}\n{{< badge >}}{{< /note >}}"), &TemplateData{}, resolver)
+	if err == nil {
+		t.Fatal("expected an error for a tag left open inside another")
+	}
+	_, err = reg.ProcessShortcodes([]byte("{{< note >}}{{< details >}}\n{{< /note >}}"), &TemplateData{}, resolver)
+	if err == nil || err.Error() != `2:1: closing tag for "note" does not match "details" opened at 1:13` {
+		t.Errorf("unexpected error for crossed tags: %v", err)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	args, err := parseArgs(` title="Say \"hi\"" alt='it\'s' code=` + "`a \\n b`" + ` n=42 bare=ok "first" open 'second' x.png`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := map[string]string{
+		"title": `Say "hi"`,
+		"alt":   "it's",
+		"code":  `a \n b`,
+		"n":     "42",
+		"bare":  "ok",
+		"open":  "true",
+	}
+	for k, want := range named {
+		if args.named[k] != want {
+			t.Errorf("named[%q] = %q, want %q", k, args.named[k], want)
+		}
+	}
+	if got := strings.Join(args.positional, "|"); got != "first|open|second|x.png" {
+		t.Errorf("positional = %q", got)
+	}
+
+	for _, bad := range []string{` title="open`, ` a=`, ` "a"b`, ` a.b=1`} {
+		if _, err := parseArgs(bad); err == nil {
+			t.Errorf("parseArgs(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestShortcodeContextAccessors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_shortcodes", "show.html"),
+		`{{ .Pos 0 }}/{{ .Pos 5 }}/{{ .GetBool "open" }}/{{ .GetBool "closed" }}/{{ .GetInt "n" }}/{{ .GetInt "bad" }}`)
+	reg, err := loadShortcodes(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := reg.ProcessShortcodes([]byte(`{{< show "first" open n=3 bad=x />}}`), &TemplateData{}, newPageResolver(nil, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "first//true/false/3/0" {
+		t.Errorf("got %q", out)
+	}
+}
+
+func TestShortcodeArgErrorPosition(t *testing.T) {
+	reg := newTestShortcodes(t)
+	_, err := reg.ProcessShortcodes([]byte("x\n{{< badge  text=\"a\" >b=1 />}}"), &TemplateData{}, newPageResolver(nil, ""))
+	if err == nil || err.Error() != `2:21: shortcode "badge": invalid argument name ">b"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBuiltinShortcodes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_shortcodes", "badge.html"), `<em>{{ .Pos 0 }}</em>`)
+	reg, err := loadShortcodes(dir, newTemplateFuncs(Config{BasePath: "/docs"}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"note", "details", "listing", "tabs", "tab", "figure", "video", "include"} {
+		if _, ok := reg.templates[name]; !ok {
+			t.Errorf("built-in shortcode %q not loaded", name)
+		}
+	}
+
+	page := &TemplateData{BasePath: "/docs"}
+	source := "{{< badge \"x\" />}}\n\n{{< figure \"/img/a.png\" alt=\"A\" />}}\n"
+	out, err := reg.ProcessShortcodes([]byte(source), page, newPageResolver(nil, "/docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "<em>x</em>") {
+		t.Errorf("expected _shortcodes/badge.html to override the built-in, got:\n%s", out)
+	}
+	if !strings.Contains(string(out), `<img src="/docs/img/a.png" alt="A"`) {
+		t.Errorf("expected figure with base path, got:\n%s", out)
+	}
+}
+
+func TestTabsShortcodeUsesNested(t *testing.T) {
+	reg, err := loadShortcodes(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := "{{< tabs >}}\n{{< tab \"One\" >}}\nFirst\n{{< /tab >}}\n{{< tab title=\"Two\" >}}\nSecond\n{{< /tab >}}\n{{< /tabs >}}\n"
+	out, err := reg.ProcessShortcodes([]byte(source), &TemplateData{}, newPageResolver(nil, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		`<button role="tab">One</button>`,
+		`<button role="tab">Two</button>`,
+		"<div role=\"tabpanel\">\n<p>First</p>\n",
+		"<div role=\"tabpanel\">\n<p>Second</p>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, `role="tabpanel"`) != 2 {
+		t.Errorf("expected exactly two panels:\n%s", got)
+	}
+}
+
+func TestIncludeShortcode(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_snippets", "a.md"), "**Included**\n")
+	writeTestFile(t, filepath.Join(dir, "_snippets", "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(filepath.Dir(dir), "secret.txt"), "secret\n")
+	reg, err := loadShortcodes(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := newPageResolver(nil, "")
+
+	out, err := reg.ProcessShortcodes([]byte("{{< include \"_snippets/a.md\" />}}\n\n{{< include file=\"/_snippets/main.go\" lang=\"go\" />}}\n"), &TemplateData{}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "**Included**\n\n~~~go\npackage main\n~~~\n"
+	if string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	// A fence inside the included file doesn't end the code block
+	writeTestFile(t, filepath.Join(dir, "_snippets", "fenced.md"), "~~~sh\nls\n~~~\n````\n")
+	out, err = reg.ProcessShortcodes([]byte(`{{< include "_snippets/fenced.md" lang="markdown" />}}`), &TemplateData{}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "~~~~~markdown\n~~~sh\nls\n~~~\n````\n~~~~~"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	_, err = reg.ProcessShortcodes([]byte(`{{< include "../secret.txt" />}}`), &TemplateData{}, resolver)
+	if err == nil {
+		t.Fatal("expected include outside the source directory to fail")
+	}
+}
+
+func TestShortcodeRelURLMethod(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "_shortcodes", "logo.html"), `<img src="{{ .RelURL "/_static/logo.svg" }}">`)
+	reg, err := loadShortcodes(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := reg.ProcessShortcodes([]byte(`{{< logo />}}`), &TemplateData{BasePath: "/docs"}, newPageResolver(nil, "/docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<img src="/docs/_static/logo.svg">`; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
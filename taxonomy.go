@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Layout variants for the pages generated for taxonomies.
+const (
+	layoutTaxonomy = "taxonomy" // A taxonomy's list of terms, e.g. /tags/
+	layoutTerm     = "term"     // The pages with one term, e.g. /tags/go/
+)
+
+// Taxonomy is a configured way of grouping pages, such as tags, with every
+// term the site's pages use.
+type Taxonomy struct {
+	Name  string // Frontmatter field and config key, e.g. "tags"
+	Title string // Display name, e.g. "Tags"
+	URL   string // Page listing the terms, e.g. "/tags/" (includes base_path)
+	Terms []Term // Sorted by name
+}
+
+// Term is one value of a taxonomy and the pages that use it.
+type Term struct {
+	Name  string     // As first written in frontmatter, e.g. "Release Notes"
+	Slug  string     // URL segment, e.g. "release-notes"
+	URL   string     // e.g. "/tags/release-notes/" (includes base_path)
+	Pages []PageMeta // In the order of .Pages
+}
+
+// TermLink is a term as listed on one of its pages.
+type TermLink struct {
+	Name string
+	URL  string // Term page URL (includes base_path)
+}
+
+// taxonomyPaths returns the configured taxonomies as name → URL path
+// without slashes. An empty path defaults to the name.
+func taxonomyPaths(cfg Config) (map[string]string, error) {
+	paths := make(map[string]string, len(cfg.Taxonomies))
+	used := make(map[string]string)
+	names := make([]string, 0, len(cfg.Taxonomies))
+	for name := range cfg.Taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := strings.Trim(cfg.Taxonomies[name], "/")
+		if p == "" {
+			p = strings.Trim(name, "/")
+		}
+		if p == "" {
+			return nil, fmt.Errorf("taxonomy %q needs a name", name)
+		}
+		if other, ok := used[p]; ok {
+			return nil, fmt.Errorf("taxonomies %q and %q both use /%s/", other, name, p)
+		}
+		used[p] = name
+		paths[name] = p
+	}
+	return paths, nil
+}
+
+// buildTaxonomies collects the terms of each taxonomy from the frontmatter
+// field of the same name, and sets Terms on metas. Terms match by slug, so
+// "Go" and "go" are one term, named as first written in metas. Different
+// names with one slug, such as "C++" and "C", would share a URL and are
+// reported as a collision.
+func buildTaxonomies(paths map[string]string, metas []PageMeta, basePath string) (map[string]Taxonomy, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	taxonomies := make(map[string]Taxonomy, len(names))
+	indexes := make(map[string]map[string]int, len(names)) // Taxonomy → slug → position in Terms
+	slugs := make([]map[string][]string, len(metas))       // Page → taxonomy → its term slugs
+	var report []string
+	for _, name := range names {
+		tax := Taxonomy{
+			Name:  name,
+			Title: TitleFromDir(name),
+			URL:   basePath + "/" + paths[name] + "/",
+		}
+		index := make(map[string]int)
+		clashes := make(map[string][]string) // Slug → every distinct name written for it
+		for i, meta := range metas {
+			for _, term := range termNames(meta.Extra[name]) {
+				slug := slugify(term)
+				if slug == "" {
+					continue
+				}
+				if _, ok := index[slug]; !ok {
+					index[slug] = len(tax.Terms)
+					tax.Terms = append(tax.Terms, Term{Name: term, Slug: slug, URL: tax.URL + slug + "/"})
+					clashes[slug] = []string{term}
+				} else if !slices.ContainsFunc(clashes[slug], func(n string) bool { return sameTerm(n, term) }) {
+					clashes[slug] = append(clashes[slug], term)
+				}
+				if slices.Contains(slugs[i][name], slug) {
+					continue
+				}
+				if slugs[i] == nil {
+					slugs[i] = make(map[string][]string)
+				}
+				slugs[i][name] = append(slugs[i][name], slug)
+			}
+		}
+		for _, term := range tax.Terms {
+			if len(clashes[term.Slug]) > 1 {
+				var lines []string
+				for _, n := range clashes[term.Slug] {
+					lines = append(lines, fmt.Sprintf("    %s: %q", name, n))
+				}
+				report = append(report, fmt.Sprintf("  %s is claimed by:\n%s", term.URL, strings.Join(lines, "\n")))
+			}
+		}
+		taxonomies[name] = tax
+		indexes[name] = index
+	}
+
+	// Set each page's terms before listing the pages, so the copies in
+	// Term.Pages carry them too
+	for i := range metas {
+		for _, name := range names {
+			for _, slug := range slugs[i][name] {
+				term := taxonomies[name].Terms[indexes[name][slug]]
+				if metas[i].Terms == nil {
+					metas[i].Terms = make(map[string][]TermLink)
+				}
+				metas[i].Terms[name] = append(metas[i].Terms[name], TermLink{Name: term.Name, URL: term.URL})
+			}
+		}
+	}
+	for _, name := range names {
+		tax := taxonomies[name]
+		for i, meta := range metas {
+			for _, slug := range slugs[i][name] {
+				term := &tax.Terms[indexes[name][slug]]
+				term.Pages = append(term.Pages, meta)
+			}
+		}
+		sort.SliceStable(tax.Terms, func(i, j int) bool {
+			return strings.ToLower(tax.Terms[i].Name) < strings.ToLower(tax.Terms[j].Name)
+		})
+	}
+	if len(report) > 0 {
+		return nil, fmt.Errorf("%d URL collision(s):\n%s", len(report), strings.Join(report, "\n"))
+	}
+	return taxonomies, nil
+}
+
+// sameTerm reports whether two names with one slug are the same term,
+// differing only in case or in how words are separated ("Release Notes",
+// "release-notes"), rather than in characters the slug drops ("C++", "C#").
+func sameTerm(a, b string) bool {
+	key := func(s string) string {
+		return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return unicode.IsSpace(r) || r == '-' || r == '_'
+		}), " ")
+	}
+	return key(a) == key(b)
+}
+
+// termNames reads a taxonomy's frontmatter field: a list of names or a
+// single name. Anything else has no terms.
+func termNames(v any) []string {
+	var items []any
+	switch v := v.(type) {
+	case string:
+		items = []any{v}
+	case []any:
+		items = v
+	default:
+		return nil
+	}
+	var names []string
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]any, []any, nil:
+			continue
+		}
+		if name := strings.TrimSpace(toText(item)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// generatedPage is a page built from site data rather than a markdown file.
+type generatedPage struct {
+	URL       string // Without base_path, e.g. "/tags/go/"
+	Title     string
+	Layout    string
+	Taxonomy  *Taxonomy
+	Term      *Term      // nil on a taxonomy's own page
+	Paginator *Paginator // This page of a term's list
+}
+
+// taxonomyPages lists the pages to generate for taxonomies: one listing the
+// terms of each, and one for every term, split into pages of pageSize pages
+// (all on one if pageSize is 0).
+func taxonomyPages(taxonomies map[string]Taxonomy, basePath string, pageSize int) []generatedPage {
+	names := make([]string, 0, len(taxonomies))
+	for name := range taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pages []generatedPage
+	for _, name := range names {
+		tax := taxonomies[name]
+		pages = append(pages, generatedPage{
+			URL:      strings.TrimPrefix(tax.URL, basePath),
+			Title:    tax.Title,
+			Layout:   layoutTaxonomy,
+			Taxonomy: &tax,
+		})
+		for i := range tax.Terms {
+			term := &tax.Terms[i]
+			for _, pager := range paginate(term.Pages, pageSize, term.URL) {
+				pages = append(pages, generatedPage{
+					URL:       strings.TrimPrefix(pager.URL, basePath),
+					Title:     term.Name,
+					Layout:    layoutTerm,
+					Taxonomy:  &tax,
+					Term:      term,
+					Paginator: &pager,
+				})
+			}
+		}
+	}
+	return pages
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildTaxonomies(t *testing.T) {
+	metas := []PageMeta{
+		{Title: "Newer", URL: "/blog/newer/", Extra: map[string]any{"tags": []any{"Go", "Release Notes"}, "categories": "News"}},
+		{Title: "Older", URL: "/blog/older/", Extra: map[string]any{"tags": []any{"go", "go", 2026, map[string]any{"x": 1}}}},
+		{Title: "Untagged", URL: "/about/"},
+	}
+	paths := map[string]string{"tags": "tags", "categories": "topics"}
+
+	taxonomies, err := buildTaxonomies(paths, metas, "/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := taxonomies["tags"]
+	if tags.Title != "Tags" || tags.URL != "/docs/tags/" {
+		t.Errorf("tags = %q at %q", tags.Title, tags.URL)
+	}
+	var terms []string
+	for _, term := range tags.Terms {
+		terms = append(terms, term.Name+"="+pageTitles(term.Pages))
+	}
+	if got := strings.Join(terms, " "); got != "2026=Older Go=Newer,Older Release Notes=Newer" {
+		t.Errorf("terms = %s", got)
+	}
+	if term := tags.Terms[1]; term.Slug != "go" || term.URL != "/docs/tags/go/" {
+		t.Errorf("go term = %+v", term)
+	}
+
+	news := metas[0].Terms["categories"]
+	if len(news) != 1 || news[0].Name != "News" || news[0].URL != "/docs/topics/news/" {
+		t.Errorf("categories of Newer = %+v", news)
+	}
+	if got := metas[1].Terms["tags"]; len(got) != 2 || got[0].Name != "Go" {
+		t.Errorf("Older has tags %+v, want Go and 2026 (duplicates dropped)", got)
+	}
+	if metas[2].Terms != nil {
+		t.Errorf("Untagged has terms %+v", metas[2].Terms)
+	}
+	// Pages listed under a term carry their terms too
+	if got := len(tags.Terms[1].Pages[0].Terms["tags"]); got != 2 {
+		t.Errorf("listed page has %d tags, want 2", got)
+	}
+}
+
+func TestTaxonomyPaths(t *testing.T) {
+	paths, err := taxonomyPaths(Config{Taxonomies: map[string]string{"tags": "/blog/tags/", "categories": ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths["tags"] != "blog/tags" || paths["categories"] != "categories" {
+		t.Errorf("paths = %v", paths)
+	}
+
+	_, err = taxonomyPaths(Config{Taxonomies: map[string]string{"tags": "labels", "labels": ""}})
+	if err == nil || !strings.Contains(err.Error(), "both use /labels/") {
+		t.Errorf("err = %v, want both use /labels/", err)
+	}
+}
+
+func TestBuildTaxonomiesReportsSlugCollisions(t *testing.T) {
+	metas := []PageMeta{
+		{Title: "A", Extra: map[string]any{"tags": []any{"C++", "Release Notes"}}},
+		{Title: "B", Extra: map[string]any{"tags": []any{"C#", "C", "release-notes"}}},
+	}
+	_, err := buildTaxonomies(map[string]string{"tags": "tags"}, metas, "")
+	if err == nil {
+		t.Fatal("expected a collision between C++, C#, and C")
+	}
+	want := "1 URL collision(s):\n  /tags/c/ is claimed by:\n    tags: \"C++\"\n    tags: \"C#\"\n    tags: \"C\""
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Default heading levels included in a page's table of contents.
+const (
+	defaultTOCMin = 2
+	defaultTOCMax = 3
+)
+
+// TOC is a page's table of contents, available to layouts as .TOC.
+type TOC struct {
+	Items []TOCItem     // Top-level headings, with deeper ones nested as Children
+	HTML  template.HTML // Ready-made <nav> with nested lists, empty when Items is
+}
+
+// TOCItem is one heading in a table of contents.
+type TOCItem struct {
+	Level    int    // 1–6
+	Text     string // Plain heading text
+	ID       string // Element id, the anchor target
+	Children []TOCItem
+}
+
+// tocTransformer records each heading with an id on the page's renderer as
+// it is parsed: level, plain text, and id. Every render for a page adds to
+// the same list, block shortcode inner content included.
+type tocTransformer struct{}
+
+func (tocTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	pr, _ := pc.Get(pageRendererKey).(*pageRenderer)
+	if pr == nil {
+		return
+	}
+	source := reader.Source()
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			pr.headings = append(pr.headings, TOCItem{
+				Level: heading.Level,
+				Text:  headingText(heading, source),
+				ID:    attrString(id),
+			})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// headingText is the plain text of a heading's inline content, with raw
+// HTML left out and whitespace collapsed.
+func headingText(heading *ast.Heading, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(heading, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			value := string(n.Segment.Value(source))
+			if _, code := n.Parent().(*ast.CodeSpan); !code {
+				value = html.UnescapeString(value)
+			}
+			b.WriteString(value)
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			if !n.IsCode() {
+				b.Write(n.Value)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// attrString returns an attribute value goldmark stored as bytes or a string.
+func attrString(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// buildTOC builds a table of contents from the headings recorded while
+// rendering a page, keeping levels minLevel through maxLevel, and nests
+// them by level. Inner content of block shortcodes is rendered before the
+// body around it, so headings are put in the order their ids appear in
+// pageHTML; one that didn't make it into the page is dropped.
+func buildTOC(headings []TOCItem, pageHTML []byte, minLevel, maxLevel int) TOC {
+	type placed struct {
+		item TOCItem
+		pos  int
+	}
+	var found []placed
+	for _, h := range headings {
+		if h.Level < minLevel || h.Level > maxLevel || h.Text == "" {
+			continue
+		}
+		pos := bytes.Index(pageHTML, []byte(` id="`+string(util.EscapeHTML([]byte(h.ID)))+`"`))
+		if pos < 0 {
+			continue
+		}
+		found = append(found, placed{h, pos})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+	flat := make([]TOCItem, len(found))
+	for i, f := range found {
+		flat[i] = f.item
+	}
+
+	items := nestTOC(flat)
+	if len(items) == 0 {
+		return TOC{}
+	}
+	var b strings.Builder
+	b.WriteString(`<nav class="toc" aria-label="On this page">`)
+	writeTOCList(&b, items)
+	b.WriteString("</nav>\n")
+	return TOC{Items: items, HTML: template.HTML(b.String())}
+}
+
+// nestTOC turns a flat heading list into a tree: each heading becomes a
+// child of the closest preceding heading with a lower level.
+func nestTOC(flat []TOCItem) []TOCItem {
+	var items []TOCItem
+	i := 0
+	for i < len(flat) {
+		item := flat[i]
+		j := i + 1
+		for j < len(flat) && flat[j].Level > item.Level {
+			j++
+		}
+		item.Children = nestTOC(flat[i+1 : j])
+		items = append(items, item)
+		i = j
+	}
+	return items
+}
+
+func writeTOCList(b *strings.Builder, items []TOCItem) {
+	b.WriteString("\n<ul>\n")
+	for _, item := range items {
+		fmt.Fprintf(b, `<li><a href="#%s">%s</a>`, html.EscapeString(item.ID), html.EscapeString(item.Text))
+		if len(item.Children) > 0 {
+			writeTOCList(b, item.Children)
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+}
+
+// parseTOCLevels parses frontmatter toc_levels: "2-3" or a single "2".
+// An empty string yields the defaults.
+func parseTOCLevels(s string) (minLevel, maxLevel int, err error) {
+	if s == "" {
+		return defaultTOCMin, defaultTOCMax, nil
+	}
+	lo, hi, found := strings.Cut(s, "-")
+	minLevel, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	maxLevel, err2 := minLevel, error(nil)
+	if found {
+		maxLevel, err2 = strconv.Atoi(strings.TrimSpace(hi))
+	}
+	if err1 != nil || err2 != nil || minLevel < 1 || maxLevel > 6 || minLevel > maxLevel {
+		return defaultTOCMin, defaultTOCMax, fmt.Errorf("invalid toc_levels %q (expected a range like 2-3)", s)
+	}
+	return minLevel, maxLevel, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderTOC renders source as a page and builds its table of contents.
+func renderTOC(t *testing.T, source string, minLevel, maxLevel int) TOC {
+	t.Helper()
+	pr := newPageResolver(nil, "").forPage("page.md", io.Discard)
+	out, err := pr.render([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buildTOC(pr.headings, out, minLevel, maxLevel)
+}
+
+func TestBuildTOCNestsByLevel(t *testing.T) {
+	toc := renderTOC(t, `# Title
+
+## Install
+
+### From `+"`source`"+` <span class="badge" data-x='a>b'>new</span>
+
+#### Too deep
+
+## Usage &amp; flags {#usage}
+`, 2, 3)
+
+	if len(toc.Items) != 2 {
+		t.Fatalf("expected 2 top-level items, got %d: %+v", len(toc.Items), toc.Items)
+	}
+	install := toc.Items[0]
+	if install.ID != "install" || len(install.Children) != 1 {
+		t.Fatalf("install = %+v, want one child", install)
+	}
+	if child := install.Children[0]; child.Text != "From source new" || child.Level != 3 {
+		t.Errorf("child = %+v, want From source new at level 3", child)
+	}
+	if toc.Items[1].Text != "Usage & flags" || toc.Items[1].ID != "usage" {
+		t.Errorf("item = %+v, want unescaped text and the explicit id", toc.Items[1])
+	}
+
+	for _, want := range []string{
+		`<nav class="toc" aria-label="On this page">`,
+		`">From source new</a>`,
+		`<a href="#usage">Usage &amp; flags</a>`,
+	} {
+		if !strings.Contains(string(toc.HTML), want) {
+			t.Errorf("HTML missing %q:\n%s", want, toc.HTML)
+		}
+	}
+	if strings.Contains(string(toc.HTML), "deep") || strings.Contains(string(toc.HTML), "#title") {
+		t.Errorf("levels outside 2-3 should be excluded:\n%s", toc.HTML)
+	}
+}
+
+func TestBuildTOCEmpty(t *testing.T) {
+	toc := renderTOC(t, "No headings\n\n<h2 id=\"raw\">Raw HTML</h2>\n", 2, 3)
+	if toc.Items != nil || toc.HTML != "" {
+		t.Errorf("expected empty TOC, got %+v", toc)
+	}
+}
+
+func TestParseTOCLevels(t *testing.T) {
+	tests := []struct {
+		in       string
+		min, max int
+		wantErr  bool
+	}{
+		{"", 2, 3, false},
+		{"2-4", 2, 4, false},
+		{"2", 2, 2, false},
+		{" 1 - 6 ", 1, 6, false},
+		{"4-2", 2, 3, true},
+		{"0-3", 2, 3, true},
+		{"h2", 2, 3, true},
+	}
+	for _, tt := range tests {
+		lo, hi, err := parseTOCLevels(tt.in)
+		if lo != tt.min || hi != tt.max || (err != nil) != tt.wantErr {
+			t.Errorf("parseTOCLevels(%q) = %d, %d, %v; want %d, %d, err=%v", tt.in, lo, hi, err, tt.min, tt.max, tt.wantErr)
+		}
+	}
+}
+
+func TestBuildTOCFrontmatter(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "on.md"), "---\ntoc_levels: 2\n---\n## Alpha\n\n### Beta\n")
+	writeTestFile(t, filepath.Join(src, "off.md"), "---\ntoc: false\n---\n## Alpha\n")
+
+	dst := t.TempDir()
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	on := readTestFile(t, filepath.Join(dst, "on", "index.html"))
+	if !strings.Contains(on, `<a href="#alpha">Alpha</a>`) || strings.Contains(on, `href="#beta"`) {
+		t.Error("expected a TOC with only level-2 headings")
+	}
+	off := readTestFile(t, filepath.Join(dst, "off", "index.html"))
+	if strings.Contains(off, `class="toc-rail"`) {
+		t.Error("toc: false should hide the TOC")
+	}
+}
+
+func TestBuildTOCIncludesShortcodeHeadings(t *testing.T) {
+	src := t.TempDir()
+	writeTestFile(t, filepath.Join(src, "page.md"), "## Before\n\n{{< details summary=\"More\" >}}\n## Inside\n{{< /details >}}\n\n## After\n")
+
+	dst := t.TempDir()
+	if err := Build(src, dst, Config{}); err != nil {
+		t.Fatal(err)
+	}
+	out := readTestFile(t, filepath.Join(dst, "page", "index.html"))
+	before := strings.Index(out, `<li><a href="#before">`)
+	inside := strings.Index(out, `<li><a href="#inside">`)
+	after := strings.Index(out, `<li><a href="#after">`)
+	if before < 0 || inside < before || after < inside {
+		t.Errorf("expected Before, Inside, After in page order, got positions %d, %d, %d", before, inside, after)
+	}
+}